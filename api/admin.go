@@ -0,0 +1,101 @@
+package api
+
+import "strings"
+
+// OrganizationUser is a user in the caller's organization, as seen by an
+// admin user.
+type OrganizationUser struct {
+	UserID    string `json:"userId"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	AdminUser bool   `json:"adminUser"`
+	Enabled   bool   `json:"enabled"`
+	LastLogin string `json:"lastLogin"`
+}
+
+// APIKey describes one of a user's API keys. The secret is never returned.
+type APIKey struct {
+	APIKeyID    string `json:"apiKeyId"`
+	Description string `json:"description"`
+	DateCreated string `json:"dateCreated"`
+	LastUsed    string `json:"lastUsed"`
+}
+
+type organizationUsers struct {
+	Users []OrganizationUser `json:"users"`
+}
+
+type apiKeys struct {
+	APIKeys []APIKey `json:"apiKeys"`
+}
+
+// The following need the caller to be an admin user, see
+// UserInformation.AdminUser.
+
+func (a *API) GetOrganizationUsers() ([]OrganizationUser, error) {
+	var u organizationUsers
+
+	err := a.requestJSON("/enterprise/users/", "GET", nil, &u)
+	if err != nil {
+		return nil, err
+	}
+	return u.Users, nil
+}
+
+// InviteUser adds a user to the organization and emails them an
+// invitation to activate their account.
+func (a *API) InviteUser(email string, firstName string, lastName string, admin bool) (OrganizationUser, error) {
+	var u OrganizationUser
+
+	postParams := make(map[string]interface{}, 4)
+	postParams["email"] = email
+	postParams["firstName"] = firstName
+	postParams["lastName"] = lastName
+	postParams["adminUser"] = admin
+
+	err := a.requestJSON("/enterprise/user/", "PUT", postParams, &u)
+	if err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+// DeactivateUser stops a user signing in or using their API keys. Their
+// packages are kept.
+func (a *API) DeactivateUser(userID string) error {
+	path := "/enterprise/user/" + userID + "/deactivate/"
+	return a.requestJSON(path, "POST", nil, nil)
+}
+
+func (a *API) GetUserAPIKeys(userID string) ([]APIKey, error) {
+	var k apiKeys
+	path := "/enterprise/user/" + userID + "/apikeys/"
+
+	err := a.requestJSON(path, "GET", nil, &k)
+	if err != nil {
+		return nil, err
+	}
+	return k.APIKeys, nil
+}
+
+func (a *API) RevokeUserAPIKey(userID string, apiKeyID string) error {
+	path := "/enterprise/user/" + userID + "/apikey/" + apiKeyID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+// FindOrganizationUser matches a user by ID, or failing that by email
+// address.
+func FindOrganizationUser(users []OrganizationUser, emailOrID string) (OrganizationUser, bool) {
+	for _, u := range users {
+		if u.UserID == emailOrID {
+			return u, true
+		}
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Email, emailOrID) {
+			return u, true
+		}
+	}
+	return OrganizationUser{}, false
+}
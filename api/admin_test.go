@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFindOrganizationUser(t *testing.T) {
+	users := []OrganizationUser{
+		{UserID: "u1", Email: "support1@test.com"},
+		{UserID: "u2", Email: "Support2@Test.com"},
+		{UserID: "support1@test.com", Email: "odd@test.com"},
+	}
+
+	tables := []struct {
+		emailOrID string
+		expected  string
+		found     bool
+	}{
+		{"u1", "u1", true},
+		{"support2@test.com", "u2", true},
+		{"support1@test.com", "support1@test.com", true},
+		{"support3@test.com", "", false},
+	}
+
+	for _, table := range tables {
+		u, found := FindOrganizationUser(users, table.emailOrID)
+		if u.UserID != table.expected || found != table.found {
+			t.Errorf("FindOrganizationUser(%s) was incorrect, got: (%s, %t), want: (%s, %t).", table.emailOrID, u.UserID, found, table.expected, table.found)
+		}
+	}
+}
+
+func TestUserAdministration(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[strings.Index(r.URL.Path, "/enterprise/"):]
+		requests = append(requests, r.Method+" "+path)
+		switch path {
+		case "/enterprise/users/":
+			w.Write([]byte(`{"response":"SUCCESS","users":[{"userId":"u1","email":"support1@test.com","enabled":true}]}`))
+		case "/enterprise/user/u1/apikeys/":
+			w.Write([]byte(`{"response":"SUCCESS","apiKeys":[{"apiKeyId":"k1","description":"gosafely"}]}`))
+		case "/enterprise/user/u2/deactivate/":
+			w.Write([]byte(`{"response":"FAIL","message":"Not an admin user"}`))
+		default:
+			w.Write([]byte(`{"response":"SUCCESS"}`))
+		}
+	}))
+	defer srv.Close()
+	a := NewAPI(srv.URL, "key", "secret")
+
+	users, err := a.GetOrganizationUsers()
+	if err != nil {
+		t.Fatalf("GetOrganizationUsers returned error: %s", err)
+	}
+	if len(users) != 1 || users[0].Email != "support1@test.com" || !users[0].Enabled {
+		t.Errorf("GetOrganizationUsers was incorrect, got: %v, want: [support1@test.com].", users)
+	}
+
+	keys, err := a.GetUserAPIKeys("u1")
+	if err != nil {
+		t.Fatalf("GetUserAPIKeys returned error: %s", err)
+	}
+	if len(keys) != 1 || keys[0].APIKeyID != "k1" {
+		t.Errorf("GetUserAPIKeys was incorrect, got: %v, want: [k1].", keys)
+	}
+
+	err = a.RevokeUserAPIKey("u1", "k1")
+	if err != nil {
+		t.Fatalf("RevokeUserAPIKey returned error: %s", err)
+	}
+	err = a.DeactivateUser("u2")
+	if err == nil || !strings.Contains(err.Error(), "Not an admin user") {
+		t.Errorf("DeactivateUser error was incorrect, got: %v, want: Not an admin user.", err)
+	}
+
+	expected := []string{"GET /enterprise/users/", "GET /enterprise/user/u1/apikeys/", "DELETE /enterprise/user/u1/apikey/k1/", "POST /enterprise/user/u2/deactivate/"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Requests were incorrect, got: %v, want: %v.", requests, expected)
+	}
+}
@@ -10,32 +10,54 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dchest/pbkdf2"
 	humanize "github.com/dustin/go-humanize"
 	"golang.org/x/crypto/openpgp"
+	"golang.org/x/time/rate"
 )
 
 var (
 	URLAPIPrefix         = "/api/v2.0"
 	URLVerifyCredentials = "/config/verify-credentials/"
 	DownloadAPI          = "JAVA_API"
+	DropzoneAPI          = "DROP_ZONE"
 	APIKeyHeader         = "ss-api-key"
 	TimestampHeader      = "ss-request-timestamp"
 	SignatureHeader      = "ss-request-signature"
+	RequestAPIHeader     = "ss-request-api"
 	ContentType          = "application/json"
+	TimestampLayout      = "Jan 2, 2006 3:04:05 PM"
+	PartSize             = int64(2621440)
 )
 
 type API struct {
 	host      string
 	apiKey    string
 	apiSecret string
+	dropzone  bool
+	logger    *slog.Logger
+
+	instrumentation Instrumentation
+	fileHooks       []FileHook
+	packageHooks    []PackageHook
+
+	bandwidth         *rate.Limiter
+	transferBandwidth int64
+
+	requests     *rate.Limiter
+	requestLimit rate.Limit
+	requestSlots chan struct{}
+	throttleMu   sync.Mutex
+	successes    int
 }
 
 type UserInformation struct {
@@ -52,43 +74,49 @@ type UserInformation struct {
 }
 
 type Package struct {
-	PackageID    string `json:"packageId"`
-	PackageCode  string `json:"packageCode"`
-	ServerSecret string `json:"serverSecret"`
-	Recipients   []struct {
-		RecipientID        string        `json:"recipientId"`
-		Email              string        `json:"email"`
-		FullName           string        `json:"fullName"`
-		NeedsApproval      bool          `json:"needsApproval"`
-		RecipientCode      string        `json:"recipientCode"`
-		Confirmations      []interface{} `json:"confirmations"`
-		IsPackageOwner     bool          `json:"isPackageOwner"`
-		CheckForPublicKeys bool          `json:"checkForPublicKeys"`
-		RoleName           string        `json:"roleName"`
-	} `json:"recipients"`
-	ContactGroups []struct {
-		ContactGroupID                  string `json:"contactGroupId"`
-		ContactGroupName                string `json:"contactGroupName"`
-		ContactGroupIsOrganizationGroup bool   `json:"contactGroupIsOrganizationGroup"`
-		Users                           []struct {
-			UserEmail string `json:"userEmail"`
-			UserID    string `json:"userId"`
-		} `json:"users"`
-	} `json:"contactGroups"`
-	Files            []File        `json:"files"`
-	Directories      []interface{} `json:"directories"`
-	ApproverList     []interface{} `json:"approverList"`
-	NeedsApproval    bool          `json:"needsApproval"`
-	State            string        `json:"state"`
-	PasswordRequired bool          `json:"passwordRequired"`
-	Life             int           `json:"life"`
-	Label            string        `json:"label"`
-	IsVDR            bool          `json:"isVDR"`
-	IsArchived       bool          `json:"isArchived"`
-	PackageSender    string        `json:"packageSender"`
-	PackageTimestamp string        `json:"packageTimestamp"`
-	RootDirectoryID  string        `json:"rootDirectoryId"`
-	Response         string        `json:"response"`
+	PackageID        string         `json:"packageId"`
+	PackageCode      string         `json:"packageCode"`
+	ServerSecret     string         `json:"serverSecret"`
+	Recipients       []Recipient    `json:"recipients"`
+	ContactGroups    []ContactGroup `json:"contactGroups"`
+	Files            []File         `json:"files"`
+	Directories      []Directory    `json:"directories"`
+	ApproverList     []Approver     `json:"approverList"`
+	NeedsApproval    bool           `json:"needsApproval"`
+	State            string         `json:"state"`
+	PasswordRequired bool           `json:"passwordRequired"`
+	Life             int            `json:"life"`
+	Label            string         `json:"label"`
+	IsVDR            bool           `json:"isVDR"`
+	IsArchived       bool           `json:"isArchived"`
+	PackageSender    string         `json:"packageSender"`
+	PackageTimestamp string         `json:"packageTimestamp"`
+	RootDirectoryID  string         `json:"rootDirectoryId"`
+	Response         string         `json:"response"`
+}
+
+type Recipient struct {
+	RecipientID        string         `json:"recipientId"`
+	Email              string         `json:"email"`
+	FullName           string         `json:"fullName"`
+	NeedsApproval      bool           `json:"needsApproval"`
+	RecipientCode      string         `json:"recipientCode"`
+	Confirmations      []Confirmation `json:"confirmations"`
+	IsPackageOwner     bool           `json:"isPackageOwner"`
+	CheckForPublicKeys bool           `json:"checkForPublicKeys"`
+	RoleName           string         `json:"roleName"`
+}
+
+type ContactGroup struct {
+	ContactGroupID                  string             `json:"contactGroupId"`
+	ContactGroupName                string             `json:"contactGroupName"`
+	ContactGroupIsOrganizationGroup bool               `json:"contactGroupIsOrganizationGroup"`
+	Users                           []ContactGroupUser `json:"users"`
+}
+
+type ContactGroupUser struct {
+	UserEmail string `json:"userEmail"`
+	UserID    string `json:"userId"`
 }
 
 type PackageMetadata struct {
@@ -106,6 +134,15 @@ type File struct {
 	FileUploadedStr string `json:"fileUploadedStr"`
 	FileVersion     string `json:"fileVersion"`
 	CreatedByEmail  string `json:"createdByEmail"`
+	DirectoryID     string `json:"directoryId"`
+}
+
+type Directory struct {
+	DirectoryID    string      `json:"directoryId"`
+	DirectoryName  string      `json:"directoryName"`
+	Files          []File      `json:"files"`
+	SubDirectories []Directory `json:"subDirectories"`
+	Response       string      `json:"response"`
 }
 
 func (f *File) FileSizeInt() uint64 {
@@ -117,28 +154,34 @@ func (f *File) FileSizeHumanize() string {
 	return humanize.Bytes(f.FileSizeInt())
 }
 
-type writeCounter struct {
-	Current  uint64
-	Total    uint64
-	Progress func(uint64, uint64)
+func (f *File) FileUploadedTime() time.Time {
+	return parseTimestamp(f.FileUploaded)
 }
 
-func (wc *writeCounter) Write(p []byte) (int, error) {
-	n := len(p)
-	wc.Current += uint64(n)
-	wc.Progress(wc.Current, wc.Total)
-	return n, nil
+// parseTimestamp parses a timestamp in TimestampLayout, returning the zero
+// time if the server sent something unexpected.
+func parseTimestamp(s string) time.Time {
+	t, _ := time.Parse(TimestampLayout, s)
+	return t
 }
 
 func NewAPI(Host string, APIKey string, APISecret string) *API {
 	c := &API{
-		host:      Host,
-		apiKey:    APIKey,
-		apiSecret: APISecret,
+		host:         Host,
+		apiKey:       APIKey,
+		apiSecret:    APISecret,
+		requests:     rate.NewLimiter(rate.Inf, 1),
+		requestLimit: rate.Inf,
 	}
 	return c
 }
 
+// SetLogger enables debug logging of requests. Secrets are redacted before
+// anything is logged.
+func (a *API) SetLogger(l *slog.Logger) {
+	a.logger = l
+}
+
 func computeHmac256(secret string, data string) string {
 	h := hmac.New(sha256.New, []byte(secret))
 	h.Write([]byte(data))
@@ -173,7 +216,12 @@ func (a *API) makeRequest(endpointURL string, method string, data []byte, stream
 		return nil, err
 	}
 
-	addCredentials(a.apiKey, a.apiSecret, req, endpointURL, data, time.Now().UTC())
+	if a.dropzone {
+		req.Header.Add(APIKeyHeader, a.apiKey)
+		req.Header.Add(RequestAPIHeader, DropzoneAPI)
+	} else {
+		addCredentials(a.apiKey, a.apiSecret, req, endpointURL, data, time.Now().UTC())
+	}
 
 	req.Header.Add("Content-Type", ContentType)
 
@@ -181,22 +229,135 @@ func (a *API) makeRequest(endpointURL string, method string, data []byte, stream
 }
 
 func (a *API) sendRequest(endpointURL string, method string, data []byte, stream bool) (io.Reader, error) {
+	for attempt := 1; ; attempt++ {
+		release, err := a.acquire()
+		if err != nil {
+			return nil, err
+		}
+
+		r, path, err := a.doRequest(endpointURL, method, data, stream)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		if r.StatusCode == http.StatusTooManyRequests && attempt <= MaxRetries {
+			r.Body.Close()
+			release()
+
+			wait := a.throttled(r.Header.Get("Retry-After"), attempt)
+			a.instrument().RequestRetried(method, path, attempt, wait)
+			if a.logger != nil {
+				a.logger.Debug("throttled, retrying", "method", method, "path", path, "attempt", attempt, "wait", wait, "rate", a.requests.Limit())
+			}
+			time.Sleep(wait)
+			continue
+		}
+
+		if r.StatusCode != 200 {
+			if a.logger != nil {
+				b, _ := ioutil.ReadAll(io.LimitReader(r.Body, 512))
+				a.logger.Debug("unexpected response", "method", method, "path", path, "status", r.StatusCode, "body", redactBody(b))
+			}
+			r.Body.Close()
+			release()
+			return nil, fmt.Errorf("Got HTTP status code: %d", r.StatusCode)
+		}
+
+		a.unthrottled()
+		return &releasingBody{ReadCloser: r.Body, release: release}, nil
+	}
+}
+
+func (a *API) doRequest(endpointURL string, method string, data []byte, stream bool) (*http.Response, string, error) {
 	req, err := a.makeRequest(endpointURL, method, data, stream)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	path := redactPath(req.URL.Path)
+	if a.logger != nil {
+		a.logger.Debug("sending request",
+			"method", method,
+			"path", path,
+			"part", requestPart(data),
+			"signed", a.apiKey+path+req.Header.Get(TimestampHeader)+redactBody(data),
+		)
 	}
 
+	a.instrument().RequestStarted(method, path)
+
+	start := time.Now()
 	client := &http.Client{}
 	r, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		a.instrument().RequestFinished(method, path, 0, time.Since(start), err)
+		if a.logger != nil {
+			a.logger.Debug("request failed", "method", method, "path", path, "duration", time.Since(start), "error", err)
+		}
+		return nil, path, err
+	}
+
+	a.instrument().RequestFinished(method, path, r.StatusCode, time.Since(start), nil)
+	if a.logger != nil {
+		a.logger.Debug("request finished", "method", method, "path", path, "part", requestPart(data), "status", r.StatusCode, "duration", time.Since(start))
+	}
+
+	return r, path, nil
+}
+
+type apiResponse struct {
+	Response string `json:"response"`
+	Message  string `json:"message"`
+}
+
+// APIError is returned when the API answers with a response other than
+// SUCCESS, e.g. DENIED. Use errors.As to check Response.
+type APIError struct {
+	Response string
+	Message  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Got API response %s: %s", e.Response, e.Message)
+}
+
+// requestJSON sends params as the JSON body of a request and decodes the
+// response into v, which may be nil if only success matters.
+func (a *API) requestJSON(endpointURL string, method string, params interface{}, v interface{}) error {
+	data := []byte{}
+	if params != nil {
+		var err error
+		data, err = json.Marshal(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	r, err := a.sendRequest(endpointURL, method, data, false)
+	if err != nil {
+		return err
+	}
+	defer closeReader(r)
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
 	}
 
-	if r.StatusCode != 200 {
-		return nil, fmt.Errorf("Got HTTP status code: %d", r.StatusCode)
+	var ar apiResponse
+	err = json.Unmarshal(b, &ar)
+	if err != nil {
+		return err
+	}
+	if ar.Response != "" && ar.Response != "SUCCESS" {
+		return &APIError{Response: ar.Response, Message: ar.Message}
 	}
 
-	return r.Body, nil
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
 }
 
 func createChecksum(keyCode string, packageCode string) string {
@@ -205,22 +366,64 @@ func createChecksum(keyCode string, packageCode string) string {
 	return fmt.Sprintf("%x", key)
 }
 
-func (a *API) DownloadFile(pm PackageMetadata, p Package, f File, fp string, progress func(uint64, uint64)) error {
+// DownloadFile downloads and decrypts f to a new file at fp.
+func (a *API) DownloadFile(pm PackageMetadata, p Package, f File, fp string, progress ProgressReporter) error {
+	return a.DownloadTo(pm, p, f, DirSink(filepath.Dir(fp)), filepath.Base(fp), progress)
+}
+
+// openPart fetches a single part of a file and returns a reader over its
+// decrypted contents. Closing it releases the underlying response body.
+// transfer, if not nil, limits the bandwidth of the file being fetched.
+func (a *API) openPart(pm PackageMetadata, p Package, f File, part int, transfer *rate.Limiter) (io.ReadCloser, error) {
+	r, err := a.fetchPart(pm, p, f, part)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &timedReader{r: a.limitReader(r, transfer)}
+	md, err := decryptMessage(body, partPassword(p.ServerSecret, pm.KeyCode))
+	if err != nil {
+		closeReader(r)
+		return nil, err
+	}
+
+	return &partReadCloser{
+		Reader:     md.UnverifiedBody,
+		body:       r,
+		timed:      body,
+		instrument: a.instrument(),
+		file:       f,
+		part:       part,
+	}, nil
+}
+
+// fetchPart requests a single part of a file and returns the response
+// body, which holds the part still encrypted.
+func (a *API) fetchPart(pm PackageMetadata, p Package, f File, part int) (io.Reader, error) {
 	method := "POST"
 	path := "/package/" + p.PackageID + "/file/" + f.FileID + "/download/"
-
-	if _, err := os.Stat(fp); !os.IsNotExist(err) {
-		return fmt.Errorf("File exists")
+	if f.DirectoryID != "" {
+		path = "/package/" + p.PackageID + "/directory/" + f.DirectoryID + "/file/" + f.FileID + "/download/"
 	}
-	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE, 0644)
+
+	postParams := make(map[string]string, 3)
+	postParams["checksum"] = createChecksum(pm.KeyCode, p.PackageCode)
+	postParams["part"] = strconv.Itoa(part)
+	postParams["api"] = DownloadAPI
+
+	pp, err := json.Marshal(postParams)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer fh.Close()
 
-	password := []byte(p.ServerSecret + pm.KeyCode)
-	cs := createChecksum(pm.KeyCode, p.PackageCode)
+	return a.sendRequest(path, method, pp, false)
+}
 
+func partPassword(serverSecret string, keyCode string) []byte {
+	return []byte(serverSecret + keyCode)
+}
+
+func decryptMessage(r io.Reader, password []byte) (*openpgp.MessageDetails, error) {
 	failed := false
 	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
 		if failed {
@@ -230,39 +433,36 @@ func (a *API) DownloadFile(pm PackageMetadata, p Package, f File, fp string, pro
 		return password, nil
 	}
 
-	counter := &writeCounter{
-		0,
-		f.FileSizeInt(),
-		progress,
-	}
-
-	for i := 1; i <= f.Parts; i++ {
-		postParams := make(map[string]string, 3)
-		postParams["checksum"] = cs
-		postParams["part"] = strconv.Itoa(i)
-		postParams["api"] = "JAVA_API"
+	return openpgp.ReadMessage(r, nil, prompt, nil)
+}
 
-		pp, err := json.Marshal(postParams)
-		if err != nil {
-			return err
-		}
+type partReadCloser struct {
+	io.Reader
+	body io.Reader
 
-		r, err := a.sendRequest(path, method, pp, false)
-		if err != nil {
-			return err
-		}
+	timed      *timedReader
+	elapsed    time.Duration
+	instrument Instrumentation
+	file       File
+	part       int
+}
 
-		failed = false
+func (p *partReadCloser) Read(b []byte) (int, error) {
+	start := time.Now()
+	n, err := p.Reader.Read(b)
+	p.elapsed += time.Since(start)
+	return n, err
+}
 
-		md, err := openpgp.ReadMessage(r, nil, prompt, nil)
-		if err != nil {
-			return err
-		}
+func (p *partReadCloser) Close() error {
+	p.instrument.PartDownloaded(p.file, p.part, p.timed.bytes)
+	p.instrument.PartDecrypted(p.file, p.part, p.elapsed-p.timed.elapsed)
+	return closeReader(p.body)
+}
 
-		_, err = io.Copy(fh, io.TeeReader(md.UnverifiedBody, counter))
-		if err != nil {
-			return err
-		}
+func closeReader(r io.Reader) error {
+	if c, ok := r.(io.Closer); ok {
+		return c.Close()
 	}
 	return nil
 }
@@ -276,6 +476,7 @@ func (a *API) UserInformation() (UserInformation, error) {
 	if err != nil {
 		return ui, err
 	}
+	defer closeReader(r)
 
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -290,6 +491,34 @@ func (a *API) UserInformation() (UserInformation, error) {
 	return ui, nil
 }
 
+func (a *API) GetDirectory(p Package, directoryID string) (Directory, error) {
+	var d Directory
+	path := "/package/" + p.PackageID + "/directory/" + directoryID + "/"
+
+	r, err := a.sendRequest(path, "GET", []byte{}, false)
+	if err != nil {
+		return d, err
+	}
+	defer closeReader(r)
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return d, err
+	}
+
+	err = json.Unmarshal(b, &d)
+	if err != nil {
+		return d, err
+	}
+
+	for i := range d.Files {
+		if d.Files[i].DirectoryID == "" {
+			d.Files[i].DirectoryID = d.DirectoryID
+		}
+	}
+	return d, nil
+}
+
 func (a *API) GetPackageMetadataFromURL(packageURL string) (PackageMetadata, error) {
 	var pm PackageMetadata
 
@@ -334,6 +563,7 @@ func (a *API) GetPackage(packageCode string) (Package, error) {
 	if err != nil {
 		return p, err
 	}
+	defer closeReader(r)
 
 	b, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -362,10 +592,3 @@ func (a *API) GetPackageFromURL(packageURL string) (Package, error) {
 
 	return p, nil
 }
-
-func ProgressPrintBytes(current uint64, total uint64) {
-	fmt.Printf("\r%s", strings.Repeat(" ", 35))
-	fmt.Printf("\r%s/%s", humanize.Bytes(current), humanize.Bytes(total))
-}
-
-func ProgressNone(current uint64, total uint64) {}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Approver is someone who can release a package held for approval, e.g.
+// by a data-loss policy on recipients outside the organization.
+type Approver struct {
+	UserID   string `json:"userId"`
+	Email    string `json:"email"`
+	FullName string `json:"fullName"`
+}
+
+// UnmarshalJSON accepts an approver given either as an object or as just
+// their email address.
+func (ap *Approver) UnmarshalJSON(b []byte) error {
+	var email string
+	if json.Unmarshal(b, &email) == nil {
+		*ap = Approver{Email: email}
+		return nil
+	}
+
+	type approver Approver
+	return json.Unmarshal(b, (*approver)(ap))
+}
+
+// PendingRecipients returns the recipients that can't access the package
+// until it has been approved.
+func (p *Package) PendingRecipients() []Recipient {
+	var pending []Recipient
+	for _, r := range p.Recipients {
+		if r.NeedsApproval {
+			pending = append(pending, r)
+		}
+	}
+	return pending
+}
+
+// IsApprover reports whether email is one of the package's approvers.
+func (p *Package) IsApprover(email string) bool {
+	for _, ap := range p.ApproverList {
+		if strings.EqualFold(ap.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+type pendingApprovals struct {
+	Packages []Package `json:"packages"`
+}
+
+// GetPendingApprovals returns the packages waiting for the caller to
+// approve or reject them.
+func (a *API) GetPendingApprovals() ([]Package, error) {
+	var pa pendingApprovals
+
+	err := a.requestJSON("/package/approvals/", "GET", nil, &pa)
+	if err != nil {
+		return nil, err
+	}
+	return pa.Packages, nil
+}
+
+// ApprovePackage releases a package to the recipients waiting for
+// approval. comment is recorded with the approval and may be empty.
+func (a *API) ApprovePackage(packageID string, comment string) error {
+	path := "/package/" + packageID + "/approve/"
+
+	postParams := make(map[string]string, 1)
+	postParams["comment"] = comment
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+// RejectPackage stops a package from ever reaching the recipients waiting
+// for approval. The sender is told the reason.
+func (a *API) RejectPackage(packageID string, reason string) error {
+	path := "/package/" + packageID + "/reject/"
+
+	postParams := make(map[string]string, 1)
+	postParams["reason"] = reason
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+// GetRecipientConfirmations returns when, and from where, a recipient
+// opened the package and downloaded each of its files.
+func (a *API) GetRecipientConfirmations(packageID string, recipientID string) ([]Confirmation, error) {
+	var r Recipient
+	path := "/package/" + packageID + "/recipient/" + recipientID + "/"
+
+	err := a.requestJSON(path, "GET", nil, &r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Confirmations, nil
+}
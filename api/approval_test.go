@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApproverList(t *testing.T) {
+	tables := []struct {
+		data     string
+		expected []string
+	}{
+		{`{"approverList":[]}`, []string{}},
+		{`{"approverList":["dlp@test.com"]}`, []string{"dlp@test.com"}},
+		{`{"approverList":[{"userId":"u1","email":"dlp@test.com"},"security@test.com"]}`, []string{"dlp@test.com", "security@test.com"}},
+	}
+
+	for _, table := range tables {
+		var p Package
+		err := json.Unmarshal([]byte(table.data), &p)
+		if err != nil {
+			t.Fatalf("Unmarshal(%s) returned error: %s", table.data, err)
+		}
+		if len(p.ApproverList) != len(table.expected) {
+			t.Fatalf("ApproverList was incorrect, got: %v, want: %v.", p.ApproverList, table.expected)
+		}
+		for i, ap := range p.ApproverList {
+			if ap.Email != table.expected[i] || !p.IsApprover(table.expected[i]) {
+				t.Errorf("ApproverList was incorrect, got: %v, want: %v.", p.ApproverList, table.expected)
+			}
+		}
+	}
+}
+
+func TestPendingRecipients(t *testing.T) {
+	p := Package{
+		Recipients: []Recipient{
+			{Email: "user1@test.com"},
+			{Email: "jane@customer.com", NeedsApproval: true},
+		},
+	}
+
+	pending := p.PendingRecipients()
+	if len(pending) != 1 || pending[0].Email != "jane@customer.com" {
+		t.Errorf("PendingRecipients was incorrect, got: %v, want: [jane@customer.com].", pending)
+	}
+}
+
+func TestApprovals(t *testing.T) {
+	var paths []string
+	var params []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]string
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &p)
+		paths = append(paths, r.Method+" "+r.URL.Path[strings.Index(r.URL.Path, "/package/"):])
+		params = append(params, p)
+
+		if r.Method == "GET" {
+			w.Write([]byte(`{"response":"SUCCESS","packages":[{"packageId":"P1","recipients":[{"email":"jane@customer.com","needsApproval":true}]}]}`))
+			return
+		}
+		w.Write([]byte(`{"response":"SUCCESS"}`))
+	}))
+	defer srv.Close()
+	a := NewAPI(srv.URL, "key", "secret")
+
+	packages, err := a.GetPendingApprovals()
+	if err != nil {
+		t.Fatalf("GetPendingApprovals returned error: %s", err)
+	}
+	if len(packages) != 1 || packages[0].PackageID != "P1" || len(packages[0].PendingRecipients()) != 1 {
+		t.Errorf("GetPendingApprovals was incorrect, got: %v, want package P1 with 1 pending recipient.", packages)
+	}
+
+	err = a.ApprovePackage("P1", "Ticket 1234")
+	if err != nil {
+		t.Fatalf("ApprovePackage returned error: %s", err)
+	}
+	err = a.RejectPackage("P2", "Contains customer data")
+	if err != nil {
+		t.Fatalf("RejectPackage returned error: %s", err)
+	}
+
+	expected := []string{"GET /package/approvals/", "POST /package/P1/approve/", "POST /package/P2/reject/"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Requests were incorrect, got: %v, want: %v.", paths, expected)
+	}
+	if len(params) == 3 && (params[1]["comment"] != "Ticket 1234" || params[2]["reason"] != "Contains customer data") {
+		t.Errorf("Request parameters were incorrect, got: %v.", params)
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+var (
+	ManifestName          = "manifest.json"
+	ManifestSignatureName = "manifest.json.asc"
+)
+
+// ArchiveManifest describes an archive of encrypted parts saved exactly as
+// they were received. It holds everything needed to decrypt the parts
+// except the keyCode, which has to be supplied separately.
+type ArchiveManifest struct {
+	Created          time.Time     `json:"created"`
+	PackageID        string        `json:"packageId"`
+	PackageCode      string        `json:"packageCode"`
+	Thread           string        `json:"thread"`
+	PackageSender    string        `json:"packageSender"`
+	PackageTimestamp string        `json:"packageTimestamp"`
+	ServerSecret     string        `json:"serverSecret"`
+	SignerKeyID      string        `json:"signerKeyId"`
+	Files            []ArchiveFile `json:"files"`
+}
+
+type ArchiveFile struct {
+	FileID   string        `json:"fileId"`
+	FileName string        `json:"fileName"`
+	FileSize string        `json:"fileSize"`
+	Parts    []ArchivePart `json:"parts"`
+}
+
+// ArchivePart is a single encrypted part. Path is relative to the archive
+// directory and uses forward slashes.
+type ArchivePart struct {
+	Part    int       `json:"part"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"`
+	Fetched time.Time `json:"fetched"`
+}
+
+func NewArchiveManifest(pm PackageMetadata, p Package) ArchiveManifest {
+	return ArchiveManifest{
+		Created:          time.Now().UTC(),
+		PackageID:        p.PackageID,
+		PackageCode:      p.PackageCode,
+		Thread:           pm.Thread,
+		PackageSender:    p.PackageSender,
+		PackageTimestamp: p.PackageTimestamp,
+		ServerSecret:     p.ServerSecret,
+		Files:            []ArchiveFile{},
+	}
+}
+
+// SaveArchiveFile saves the encrypted parts of f under dir, in a directory
+// named after the file ID so files with the same name don't collide.
+func (a *API) SaveArchiveFile(pm PackageMetadata, p Package, f File, dir string, progress ProgressReporter) (ArchiveFile, error) {
+	af := ArchiveFile{
+		FileID:   f.FileID,
+		FileName: f.FileName,
+		FileSize: f.FileSize,
+	}
+
+	fileDir := filepath.Join(dir, baseName(f.FileID))
+	err := os.MkdirAll(fileDir, 0700)
+	if err != nil {
+		return af, err
+	}
+
+	parts, err := a.saveParts(pm, p, f, fileDir, progress)
+	for _, part := range parts {
+		part.Path = path.Join(baseName(f.FileID), filepath.Base(part.Path))
+		af.Parts = append(af.Parts, part)
+	}
+	return af, err
+}
+
+// WriteManifest writes m to dir along with a detached signature made with
+// kp, so the archive can later be shown to be unaltered.
+func WriteManifest(dir string, m ArchiveManifest, kp KeyPair) error {
+	m.SignerKeyID = kp.Entity.PrimaryKey.KeyIdString()
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	var sig bytes.Buffer
+	err = openpgp.ArmoredDetachSign(&sig, kp.Entity, bytes.NewReader(b), encryptionConfig)
+	if err != nil {
+		return err
+	}
+
+	err = writeNewFile(filepath.Join(dir, ManifestName), b)
+	if err != nil {
+		return err
+	}
+	return writeNewFile(filepath.Join(dir, ManifestSignatureName), sig.Bytes())
+}
+
+// ReadArchiveManifest reads the manifest in dir without checking its
+// signature. Use VerifyArchive to check the archive hasn't been altered.
+func ReadArchiveManifest(dir string) (ArchiveManifest, error) {
+	var m ArchiveManifest
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return m, err
+	}
+
+	for _, f := range m.Files {
+		for _, part := range f.Parts {
+			if !fs.ValidPath(part.Path) {
+				return m, fmt.Errorf("Invalid part path %s", part.Path)
+			}
+		}
+	}
+	return m, nil
+}
+
+// VerifyArchive checks the manifest in dir was signed by a key in keyring
+// and that every part still matches the hash recorded for it.
+func VerifyArchive(dir string, keyring openpgp.EntityList) (ArchiveManifest, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	sig, err := os.Open(filepath.Join(dir, ManifestSignatureName))
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+	defer sig.Close()
+
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(b), sig)
+	if err != nil {
+		return ArchiveManifest{}, err
+	}
+
+	m, err := ReadArchiveManifest(dir)
+	if err != nil {
+		return m, err
+	}
+
+	for _, f := range m.Files {
+		for _, part := range f.Parts {
+			err = verifyPart(dir, part)
+			if err != nil {
+				return m, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// PartPaths returns the paths of the parts of f, in part order.
+func (f ArchiveFile) PartPaths(dir string) []string {
+	paths := make([]string, len(f.Parts))
+	for i, part := range f.Parts {
+		paths[i] = filepath.Join(dir, filepath.FromSlash(part.Path))
+	}
+	return paths
+}
+
+func verifyPart(dir string, part ArchivePart) error {
+	fh, err := os.Open(filepath.Join(dir, filepath.FromSlash(part.Path)))
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, fh)
+	if err != nil {
+		return err
+	}
+	if n != part.Size || hex.EncodeToString(h.Sum(nil)) != part.SHA256 {
+		return fmt.Errorf("%s does not match the manifest", part.Path)
+	}
+	return nil
+}
+
+func writeNewFile(fp string, b []byte) error {
+	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("File exists")
+	}
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	_, err = fh.Write(b)
+	if err != nil {
+		return err
+	}
+	return fh.Close()
+}
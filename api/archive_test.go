@@ -0,0 +1,150 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+func testArchive(t *testing.T, kp KeyPair, keyCode string) string {
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pm := PackageMetadata{Thread: "ABCD-EFGH", PackageCode: "11aa22bb33cc", KeyCode: keyCode}
+	p := Package{PackageID: "ABCD-EFGH", PackageCode: "11aa22bb33cc", ServerSecret: "secret"}
+	f := File{FileID: "1", FileName: "logs.tgz", FileSize: "22", Parts: 2}
+
+	err = os.Mkdir(filepath.Join(dir, f.FileID), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := writeEncryptedParts(t, filepath.Join(dir, f.FileID), f, partPassword(p.ServerSecret, keyCode), [][]byte{
+		[]byte("first part,"),
+		[]byte("second part"),
+	})
+
+	af := ArchiveFile{FileID: f.FileID, FileName: f.FileName, FileSize: f.FileSize}
+	for i, fp := range paths {
+		b, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(b)
+		af.Parts = append(af.Parts, ArchivePart{
+			Part:   i + 1,
+			Path:   path.Join(f.FileID, filepath.Base(fp)),
+			Size:   int64(len(b)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	m := NewArchiveManifest(pm, p)
+	m.Files = append(m.Files, af)
+	err = WriteManifest(dir, m, kp)
+	if err != nil {
+		t.Fatalf("WriteManifest returned error: %s", err)
+	}
+	return dir
+}
+
+func TestVerifyArchive(t *testing.T) {
+	kp, err := GenerateKeyPair("Evidence Bot", "evidence@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateKeyPair("Someone Else", "else@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []struct {
+		name    string
+		keyring openpgp.EntityList
+		tamper  func(dir string) error
+		err     bool
+	}{
+		{"unaltered", openpgp.EntityList{kp.Entity}, nil, false},
+		{"wrong key", openpgp.EntityList{other.Entity}, nil, true},
+		{"altered part", openpgp.EntityList{kp.Entity}, func(dir string) error {
+			return ioutil.WriteFile(filepath.Join(dir, "1", "logs.tgz.part2"), []byte("tampered"), 0644)
+		}, true},
+		{"altered manifest", openpgp.EntityList{kp.Entity}, func(dir string) error {
+			fp := filepath.Join(dir, ManifestName)
+			b, err := ioutil.ReadFile(fp)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(fp, []byte(strings.Replace(string(b), "logs.tgz\"", "other.tgz\"", 1)), 0644)
+		}, true},
+	}
+
+	for _, table := range tables {
+		dir := testArchive(t, kp, "keycode")
+		if table.tamper != nil {
+			if err := table.tamper(dir); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		m, err := VerifyArchive(dir, table.keyring)
+		os.RemoveAll(dir)
+		if table.err {
+			if err == nil {
+				t.Errorf("VerifyArchive(%s) should have failed.", table.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("VerifyArchive(%s) returned error: %s", table.name, err)
+			continue
+		}
+		if m.SignerKeyID != kp.Entity.PrimaryKey.KeyIdString() || len(m.Files) != 1 || len(m.Files[0].Parts) != 2 {
+			t.Errorf("VerifyArchive(%s) manifest was incorrect, got: %+v", table.name, m)
+		}
+	}
+}
+
+func TestArchiveDecrypt(t *testing.T) {
+	kp, err := GenerateKeyPair("Evidence Bot", "evidence@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyCode := "dd44ee55ff66"
+
+	dir := testArchive(t, kp, keyCode)
+	defer os.RemoveAll(dir)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), keyCode) {
+		t.Errorf("Manifest contains the keyCode: %s", b)
+	}
+
+	m, err := ReadArchiveManifest(dir)
+	if err != nil {
+		t.Fatalf("ReadArchiveManifest returned error: %s", err)
+	}
+	fp := filepath.Join(dir, "logs.tgz")
+	err = DecryptParts(m.ServerSecret, keyCode, m.Files[0].PartPaths(dir), fp)
+	if err != nil {
+		t.Fatalf("DecryptParts returned error: %s", err)
+	}
+	b, err = ioutil.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "first part,second part" {
+		t.Errorf("Decrypted archive was incorrect, got: %q, want: %q.", b, "first part,second part")
+	}
+}
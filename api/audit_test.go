@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfirmations(t *testing.T) {
+	var r Recipient
+	err := json.Unmarshal([]byte(`{"email":"jane@customer.com","confirmations":[{"ipAddress":"10.0.0.1","timestamp":"Nov 4, 2018 10:51:02 PM","fileId":"file-1","fileName":"5mb.dat","isMessage":false}]}`), &r)
+	if err != nil {
+		t.Fatalf("Unmarshal returned error: %s", err)
+	}
+	if len(r.Confirmations) != 1 {
+		t.Fatalf("Confirmations was incorrect, got: %v, want 1 confirmation.", r.Confirmations)
+	}
+	c := r.Confirmations[0]
+	expected := time.Date(2018, 11, 4, 22, 51, 2, 0, time.UTC)
+	if c.IPAddress != "10.0.0.1" || c.FileName != "5mb.dat" || !c.Time().Equal(expected) {
+		t.Errorf("Confirmation was incorrect, got: %+v, want a download of 5mb.dat from 10.0.0.1 at %s.", c, expected)
+	}
+}
+
+func TestActivityLog(t *testing.T) {
+	tables := []struct {
+		events   int
+		pageSize int
+		requests int
+	}{
+		{0, 10, 1},
+		{5, 10, 1},
+		{10, 10, 2},
+		{25, 10, 3},
+	}
+
+	for _, table := range tables {
+		var requests []map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var params map[string]interface{}
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &params)
+			requests = append(requests, params)
+
+			if !strings.HasSuffix(r.URL.Path, "/package/P1/activityLog/") {
+				http.NotFound(w, r)
+				return
+			}
+			var l activityLog
+			for i := int(params["rowIndex"].(float64)); i < table.events && len(l.Entries) < table.pageSize; i++ {
+				l.Entries = append(l.Entries, ActivityEvent{Event: "DOWNLOAD", Description: fmt.Sprint(i)})
+			}
+			json.NewEncoder(w).Encode(l)
+		}))
+		a := NewAPI(srv.URL, "key", "secret")
+
+		var got []string
+		q := ActivityQuery{PackageID: "P1", From: time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC), PageSize: table.pageSize}
+		err := a.ActivityLog(q, func(e ActivityEvent) error {
+			got = append(got, e.PackageID+":"+e.Description)
+			return nil
+		})
+		srv.Close()
+		if err != nil {
+			t.Fatalf("ActivityLog(%d) returned error: %s", table.events, err)
+		}
+
+		if len(got) != table.events || len(requests) != table.requests {
+			t.Errorf("ActivityLog(%d) was incorrect, got: %d events in %d requests, want: %d events in %d requests.", table.events, len(got), len(requests), table.events, table.requests)
+		}
+		for i, e := range got {
+			if e != fmt.Sprintf("P1:%d", i) {
+				t.Errorf("ActivityLog(%d) event %d was incorrect, got: %s, want: P1:%d.", table.events, i, e, i)
+			}
+		}
+		if requests[0]["fromDate"] != "11/01/2018" || requests[0]["toDate"] != nil {
+			t.Errorf("ActivityLog(%d) dates were incorrect, got: %v.", table.events, requests[0])
+		}
+	}
+}
+
+func TestActivityLogIgnoredRowIndex(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"response":"SUCCESS","activityLogEntries":[{"event":"DOWNLOAD","description":"0"},{"event":"DOWNLOAD","description":"1"}]}`))
+	}))
+	defer srv.Close()
+	a := NewAPI(srv.URL, "key", "secret")
+
+	events := 0
+	err := a.ActivityLog(ActivityQuery{PackageID: "P1", PageSize: 2}, func(e ActivityEvent) error {
+		events++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ActivityLog returned error: %s", err)
+	}
+	if events != 2 || requests != 2 {
+		t.Errorf("ActivityLog was incorrect, got: %d events in %d requests, want: 2 events in 2 requests.", events, requests)
+	}
+}
+
+func TestActivityWriter(t *testing.T) {
+	e := ActivityEvent{
+		Timestamp:   "Nov 4, 2018 10:51:02 PM",
+		PackageID:   "P1",
+		Event:       "DOWNLOAD",
+		Email:       "jane@customer.com",
+		IPAddress:   "10.0.0.1",
+		FileName:    "5mb.dat",
+		Description: "Downloaded, from the office",
+	}
+
+	tables := []struct {
+		format   string
+		expected string
+	}{
+		{ExportJSONL, `{"timestamp":"Nov 4, 2018 10:51:02 PM","packageId":"P1","event":"DOWNLOAD","email":"jane@customer.com","ipAddress":"10.0.0.1","fileId":"","fileName":"5mb.dat","description":"Downloaded, from the office"}` + "\n"},
+		{ExportCSV, "timestamp,packageId,event,email,ipAddress,fileId,fileName,description\n" + `"Nov 4, 2018 10:51:02 PM",P1,DOWNLOAD,jane@customer.com,10.0.0.1,,5mb.dat,"Downloaded, from the office"` + "\n"},
+	}
+
+	for _, table := range tables {
+		var buf bytes.Buffer
+		w, err := NewActivityWriter(&buf, table.format)
+		if err != nil {
+			t.Fatalf("NewActivityWriter(%s) returned error: %s", table.format, err)
+		}
+		w.Write(e)
+		err = w.Flush()
+		if err != nil {
+			t.Fatalf("Flush returned error: %s", err)
+		}
+		if buf.String() != table.expected {
+			t.Errorf("ActivityWriter(%s) output was incorrect, got: %s, want: %s.", table.format, buf.String(), table.expected)
+		}
+	}
+
+	_, err := NewActivityWriter(&bytes.Buffer{}, "xml")
+	if err == nil {
+		t.Errorf("NewActivityWriter(xml) did not return an error")
+	}
+}
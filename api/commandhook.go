@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CommandHook runs a shell command after each file or package download.
+// The command gets a JSON description of what was downloaded on stdin,
+// and the main details in GOSAFELY_* environment variables:
+//
+//	GOSAFELY_EVENT           file or package
+//	GOSAFELY_PACKAGE_ID      GOSAFELY_PACKAGE_CODE
+//	GOSAFELY_PACKAGE_SENDER  GOSAFELY_FILE_COUNT (package events)
+//	GOSAFELY_FILE_ID         GOSAFELY_FILE_NAME   GOSAFELY_FILE_SIZE
+//	GOSAFELY_FILE_PATH       GOSAFELY_FILE_SHA256 (file events)
+//
+// A command that exits non-zero fails the hook, with its output in the
+// error. The downloaded files are left as they are. SS_API_* variables,
+// which hold the API credentials, are not passed on.
+type CommandHook struct {
+	Command string
+	Timeout time.Duration
+}
+
+func NewCommandHook(command string) *CommandHook {
+	return &CommandHook{Command: command, Timeout: 10 * time.Minute}
+}
+
+func (h *CommandHook) Name() string {
+	return h.Command
+}
+
+func (h *CommandHook) FileDownloaded(d DownloadedFile) error {
+	env := append(packageEnv("file", d.Package),
+		"GOSAFELY_FILE_ID="+d.File.FileID,
+		"GOSAFELY_FILE_NAME="+d.File.FileName,
+		"GOSAFELY_FILE_SIZE="+d.File.FileSize,
+		"GOSAFELY_FILE_PATH="+d.Path,
+		"GOSAFELY_FILE_SHA256="+d.SHA256,
+	)
+	return h.run(env, struct {
+		Event string `json:"event"`
+		DownloadedFile
+	}{"file", d})
+}
+
+func (h *CommandHook) PackageDownloaded(p Package, files []DownloadedFile) error {
+	env := append(packageEnv("package", p), fmt.Sprintf("GOSAFELY_FILE_COUNT=%d", len(files)))
+	if files == nil {
+		files = []DownloadedFile{}
+	}
+
+	// The package is already given once, so leave it out of each file.
+	type file struct {
+		File   File   `json:"file"`
+		Path   string `json:"path"`
+		SHA256 string `json:"sha256"`
+	}
+	fs := make([]file, len(files))
+	for i, f := range files {
+		fs[i] = file{f.File, f.Path, f.SHA256}
+	}
+	return h.run(env, struct {
+		Event   string  `json:"event"`
+		Package Package `json:"package"`
+		Files   []file  `json:"files"`
+	}{"package", p, fs})
+}
+
+func packageEnv(event string, p Package) []string {
+	return []string{
+		"GOSAFELY_EVENT=" + event,
+		"GOSAFELY_PACKAGE_ID=" + p.PackageID,
+		"GOSAFELY_PACKAGE_CODE=" + p.PackageCode,
+		"GOSAFELY_PACKAGE_SENDER=" + p.PackageSender,
+	}
+}
+
+func (h *CommandHook) run(env []string, v interface{}) error {
+	stdin, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Command)
+	}
+	var out bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.Env = append(hookEnviron(), env...)
+
+	err = cmd.Run()
+	if err != nil {
+		if msg := lastLine(out.String()); msg != "" {
+			return fmt.Errorf("%s: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// hookEnviron is the environment hook commands inherit, without the
+// SendSafely API credentials: hooks are told about a download, they don't
+// get to act as the user.
+func hookEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "SS_API_") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCommandHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test commands need sh")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	d := DownloadedFile{
+		Package: Package{PackageID: "ABCD-EFGH", PackageCode: "11aa22bb33cc"},
+		File:    File{FileID: "file-1", FileName: "5mb.dat", FileSize: "5242880"},
+		Path:    "/tmp/5mb.dat",
+		SHA256:  "e3b0c44298fc1c149afbf4c8996fb924",
+	}
+
+	tables := []struct {
+		command  string
+		pkg      bool
+		expected string
+		err      string
+	}{
+		{`echo "$GOSAFELY_EVENT $GOSAFELY_PACKAGE_ID $GOSAFELY_FILE_NAME $GOSAFELY_FILE_PATH $GOSAFELY_FILE_SHA256" > ` + out, false, "file ABCD-EFGH 5mb.dat /tmp/5mb.dat e3b0c44298fc1c149afbf4c8996fb924\n", ""},
+		{"cat > " + out, false, `{"event":"file","package":{`, ""},
+		{`echo "$GOSAFELY_EVENT $GOSAFELY_PACKAGE_CODE $GOSAFELY_FILE_COUNT" > ` + out, true, "package 11aa22bb33cc 1\n", ""},
+		{"cat > " + out, true, `{"event":"package","package":{`, ""},
+		{"echo Disk full >&2; exit 2", false, "", "exit status 2: Disk full"},
+	}
+
+	for _, table := range tables {
+		os.Remove(out)
+		h := NewCommandHook(table.command)
+
+		var err error
+		if table.pkg {
+			err = h.PackageDownloaded(d.Package, []DownloadedFile{d})
+		} else {
+			err = h.FileDownloaded(d)
+		}
+		if table.err != "" {
+			if err == nil || err.Error() != table.err {
+				t.Errorf("CommandHook error was incorrect, got: %v, want: %s.", err, table.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CommandHook %s returned error: %s", table.command, err)
+			continue
+		}
+
+		got, _ := os.ReadFile(out)
+		if !strings.HasPrefix(string(got), table.expected) {
+			t.Errorf("CommandHook output was incorrect, got: %s, want: %s.", got, table.expected)
+		}
+		if strings.Contains(string(got), "{") && !strings.Contains(string(got), `"sha256":"e3b0c44298fc1c149afbf4c8996fb924"`) {
+			t.Errorf("CommandHook JSON was missing the hash, got: %s.", got)
+		}
+	}
+}
+
+func TestCommandHookEnviron(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test commands need sh")
+	}
+	out := filepath.Join(t.TempDir(), "env")
+	os.Setenv("SS_API_KEY_SECRET", "hook-must-not-see-this")
+	defer os.Unsetenv("SS_API_KEY_SECRET")
+
+	err := NewCommandHook("env > " + out).FileDownloaded(DownloadedFile{Path: "/tmp/5mb.dat"})
+	if err != nil {
+		t.Fatalf("CommandHook returned error: %s", err)
+	}
+	got, _ := os.ReadFile(out)
+	if strings.Contains(string(got), "SS_API_") || !strings.Contains(string(got), "GOSAFELY_FILE_PATH=/tmp/5mb.dat") {
+		t.Errorf("CommandHook environment was incorrect, got: %s, want GOSAFELY_* without SS_API_*.", got)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+// NewDropzoneAPI returns an API that can only submit packages to the
+// Dropzone with the given ID. Dropzone requests aren't signed, so no API
+// key or secret is needed.
+func NewDropzoneAPI(Host string, DropzoneID string) *API {
+	c := NewAPI(Host, DropzoneID, "")
+	c.dropzone = true
+	return c
+}
+
+// SubmitDropzone uploads files and an optional message to the Dropzone as
+// a new package from name and email. It returns the package and its
+// secure link.
+func (a *API) SubmitDropzone(name string, email string, message string, files []string, progress ProgressReporter) (Package, string, error) {
+	p, pm, err := a.CreatePackage()
+	if err != nil {
+		return p, "", err
+	}
+
+	for _, fp := range files {
+		f, err := a.UploadFile(pm, p, fp, progress)
+		if err != nil {
+			return p, "", err
+		}
+		p.Files = append(p.Files, f)
+	}
+
+	if message != "" {
+		err = a.SavePackageMessage(pm, p, message)
+		if err != nil {
+			return p, "", err
+		}
+	}
+
+	postParams := make(map[string]string, 3)
+	postParams["name"] = name
+	postParams["email"] = email
+
+	link, err := a.finalize(pm, p, postParams)
+	return p, link, err
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultExtractLimit is the most ExtractArchive will write unless told
+// otherwise, to stop a small archive expanding to fill the disk.
+var DefaultExtractLimit = int64(10 * 1024 * 1024 * 1024)
+
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar.zst", ".tzst", ".tar", ".zip"}
+
+type ExtractOptions struct {
+	// Limit is the most bytes that may be extracted, DefaultExtractLimit
+	// if zero.
+	Limit int64
+	// Remove deletes the archive once it has been extracted.
+	Remove bool
+}
+
+// IsArchive reports whether name looks like an archive ExtractArchive can
+// unpack.
+func IsArchive(name string) bool {
+	return archiveExtension(name) != ""
+}
+
+func archiveExtension(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// ExtractArchive unpacks the archive at fp into a new directory next to
+// it, named after the archive, and returns the directory. Entries that
+// would land outside that directory are refused, as are links; nothing
+// that already exists is overwritten. If extraction fails the directory
+// is removed.
+func ExtractArchive(fp string, opts ExtractOptions) (string, error) {
+	ext := archiveExtension(fp)
+	if ext == "" {
+		return "", fmt.Errorf("%s is not a supported archive", filepath.Base(fp))
+	}
+	if opts.Limit == 0 {
+		opts.Limit = DefaultExtractLimit
+	}
+
+	dest := fp[:len(fp)-len(ext)]
+	err := os.Mkdir(dest, 0755)
+	if os.IsExist(err) {
+		return "", fmt.Errorf("%s: File exists", dest)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	x := &extractor{dest: dest, remaining: opts.Limit, limit: opts.Limit}
+	if ext == ".zip" {
+		err = x.zip(fp)
+	} else {
+		err = x.tar(fp, ext)
+	}
+	if err != nil {
+		os.RemoveAll(dest)
+		return "", err
+	}
+
+	if opts.Remove {
+		err = os.Remove(fp)
+	}
+	return dest, err
+}
+
+type extractor struct {
+	dest      string
+	remaining int64
+	limit     int64
+}
+
+func (x *extractor) zip(fp string) error {
+	zr, err := zip.OpenReader(fp)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.mkdir(f.Name)
+		case mode.IsRegular():
+			err = x.zipFile(f)
+		default:
+			err = fmt.Errorf("%s: Refusing to extract %s", f.Name, mode.Type())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) zipFile(f *zip.File) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return x.file(f.Name, r, f.Mode())
+}
+
+func (x *extractor) tar(fp string, ext string) error {
+	fh, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var r io.Reader = fh
+	switch ext {
+	case ".tar.gz", ".tgz":
+		gr, err := gzip.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	case ".tar.zst", ".tzst":
+		zr, err := zstd.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(hdr.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = x.file(hdr.Name, tr, hdr.FileInfo().Mode())
+		case tar.TypeXGlobalHeader:
+		default:
+			err = fmt.Errorf("%s: Refusing to extract tar entry of type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// path returns where name should be extracted to, refusing names that
+// would escape the destination directory.
+func (x *extractor) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")))
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: Refusing to extract outside %s", name, x.dest)
+	}
+	return filepath.Join(x.dest, clean), nil
+}
+
+func (x *extractor) mkdir(name string) error {
+	fp, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(fp, 0755)
+}
+
+func (x *extractor) file(name string, r io.Reader, mode os.FileMode) error {
+	fp, err := x.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+
+	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm()|0600)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s: File exists", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	// Read one byte past the limit so an archive that exceeds it can be
+	// told apart from one that fills it exactly.
+	n, err := io.Copy(fh, io.LimitReader(r, x.remaining+1))
+	if err != nil {
+		return err
+	}
+	if n > x.remaining {
+		return fmt.Errorf("Archive exceeds the extraction limit of %s", humanize.IBytes(uint64(x.limit)))
+	}
+	x.remaining -= n
+	return fh.Close()
+}
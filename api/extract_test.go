@@ -0,0 +1,153 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type testEntry struct {
+	name string
+	body string
+	link bool
+}
+
+func writeTestArchive(t *testing.T, fp string, entries []testEntry) {
+	var buf bytes.Buffer
+	lower := strings.ToLower(fp)
+
+	if strings.HasSuffix(lower, ".zip") {
+		zw := zip.NewWriter(&buf)
+		for _, e := range entries {
+			w, err := zw.Create(e.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(e.body))
+		}
+		zw.Close()
+	} else {
+		var tw *tar.Writer
+		var closer interface{ Close() error }
+		switch {
+		case strings.HasSuffix(lower, ".tar.gz"):
+			gw := gzip.NewWriter(&buf)
+			tw, closer = tar.NewWriter(gw), gw
+		case strings.HasSuffix(lower, ".tar.zst"):
+			zw, err := zstd.NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw, closer = tar.NewWriter(zw), zw
+		default:
+			tw = tar.NewWriter(&buf)
+		}
+		for _, e := range entries {
+			hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+			if e.link {
+				hdr = &tar.Header{Name: e.name, Linkname: e.body, Typeflag: tar.TypeSymlink}
+			}
+			tw.WriteHeader(hdr)
+			if !e.link {
+				tw.Write([]byte(e.body))
+			}
+		}
+		tw.Close()
+		if closer != nil {
+			closer.Close()
+		}
+	}
+
+	err := ioutil.WriteFile(fp, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	bundle := []testEntry{{"logs/app.log", "app", false}, {"notes.txt", "notes", false}}
+
+	tables := []struct {
+		archive string
+		entries []testEntry
+		opts    ExtractOptions
+		err     string
+	}{
+		{"bundle.zip", bundle, ExtractOptions{}, ""},
+		{"bundle.tar", bundle, ExtractOptions{}, ""},
+		{"bundle.tar.gz", bundle, ExtractOptions{Remove: true}, ""},
+		{"bundle.tar.zst", bundle, ExtractOptions{}, ""},
+		{"bundle.zip", bundle, ExtractOptions{Limit: 8}, ""},
+		{"slip.zip", []testEntry{{"../../etc/passwd", "root", false}}, ExtractOptions{}, "Refusing to extract outside"},
+		{"slip.tar.gz", []testEntry{{"/etc/passwd", "root", false}}, ExtractOptions{}, "Refusing to extract outside"},
+		{"link.tar", []testEntry{{"passwd", "/etc/passwd", true}}, ExtractOptions{}, "Refusing to extract"},
+		{"bomb.tar.zst", []testEntry{{"big.bin", strings.Repeat("0", 4096), false}}, ExtractOptions{Limit: 1024}, "exceeds the extraction limit"},
+		{"bomb.zip", bundle, ExtractOptions{Limit: 7}, "exceeds the extraction limit"},
+	}
+
+	for _, table := range tables {
+		dir, err := ioutil.TempDir("", "gosafely")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp := filepath.Join(dir, table.archive)
+		writeTestArchive(t, fp, table.entries)
+
+		dest, err := ExtractArchive(fp, table.opts)
+		if table.err != "" {
+			if err == nil || !strings.Contains(err.Error(), table.err) {
+				t.Errorf("ExtractArchive(%s) error was incorrect, got: %v, want: %s.", table.archive, err, table.err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, strings.SplitN(table.archive, ".", 2)[0])); !os.IsNotExist(err) {
+				t.Errorf("ExtractArchive(%s) should have removed the partial extraction.", table.archive)
+			}
+			os.RemoveAll(dir)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ExtractArchive(%s) returned error: %s", table.archive, err)
+		}
+
+		if dest != filepath.Join(dir, "bundle") {
+			t.Errorf("ExtractArchive(%s) destination was incorrect, got: %s", table.archive, dest)
+		}
+		for _, e := range table.entries {
+			b, err := ioutil.ReadFile(filepath.Join(dest, filepath.FromSlash(e.name)))
+			if err != nil || string(b) != e.body {
+				t.Errorf("ExtractArchive(%s) %s was incorrect, got: (%q, %v), want: %q.", table.archive, e.name, b, err, e.body)
+			}
+		}
+		if _, err := os.Stat(fp); os.IsNotExist(err) != table.opts.Remove {
+			t.Errorf("ExtractArchive(%s) archive removal was incorrect, removed: %t, want: %t.", table.archive, os.IsNotExist(err), table.opts.Remove)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tables := []struct {
+		name     string
+		expected bool
+	}{
+		{"bundle.zip", true},
+		{"bundle.TAR.GZ", true},
+		{"bundle.tgz", true},
+		{"bundle.tar.zst", true},
+		{"heap.dump", false},
+		{"logs.gz", false},
+	}
+
+	for _, table := range tables {
+		if IsArchive(table.name) != table.expected {
+			t.Errorf("IsArchive(%s) was incorrect, want: %t.", table.name, table.expected)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// PackageFS is a read-only fs.FS view of a package. Files are downloaded
+// and decrypted lazily, one part at a time, as they are read.
+type PackageFS struct {
+	api *API
+	pm  PackageMetadata
+	p   Package
+
+	mu   sync.Mutex
+	dirs map[string]Directory
+	// starts holds, for each file ID, the offsets the parts read so far
+	// start at. Parts aren't guaranteed to be the same size, so these are
+	// learned from the decrypted parts rather than worked out.
+	starts map[string][]int64
+}
+
+func NewPackageFS(a *API, pm PackageMetadata, p Package) *PackageFS {
+	return &PackageFS{
+		api:    a,
+		pm:     pm,
+		p:      p,
+		dirs:   make(map[string]Directory),
+		starts: make(map[string][]int64),
+	}
+}
+
+// fsNode is either a File or a Directory found while resolving a path.
+type fsNode struct {
+	name string
+	file *File
+	dir  *Directory
+}
+
+func (pfs *PackageFS) Open(name string) (fs.File, error) {
+	n, err := pfs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.file != nil {
+		return &packageFile{fs: pfs, info: fileInfo{n}, transfer: pfs.api.newTransferLimiter()}, nil
+	}
+
+	entries, err := pfs.entries(n)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &packageDir{info: fileInfo{n}, entries: entries}, nil
+}
+
+func (pfs *PackageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n, err := pfs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.dir == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := pfs.entries(n)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+func (pfs *PackageFS) Stat(name string) (fs.FileInfo, error) {
+	n, err := pfs.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{n}, nil
+}
+
+func (pfs *PackageFS) lookup(op string, name string) (fsNode, error) {
+	if !fs.ValidPath(name) {
+		return fsNode{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	root := Directory{
+		DirectoryID:    pfs.p.RootDirectoryID,
+		Files:          pfs.p.Files,
+		SubDirectories: pfs.p.Directories,
+	}
+	n := fsNode{name: ".", dir: &root}
+	if name == "." {
+		return n, nil
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if n.dir == nil {
+			return fsNode{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		d, err := pfs.load(*n.dir)
+		if err != nil {
+			return fsNode{}, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		next, ok := findNode(d, elem)
+		if !ok {
+			return fsNode{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		n = next
+	}
+	return n, nil
+}
+
+// load returns the contents of d, fetching them from the server if the
+// package listing only included the directory itself.
+func (pfs *PackageFS) load(d Directory) (Directory, error) {
+	if d.Files != nil || d.SubDirectories != nil || d.DirectoryID == "" {
+		return d, nil
+	}
+
+	pfs.mu.Lock()
+	defer pfs.mu.Unlock()
+
+	if cached, ok := pfs.dirs[d.DirectoryID]; ok {
+		return cached, nil
+	}
+
+	loaded, err := pfs.api.GetDirectory(pfs.p, d.DirectoryID)
+	if err != nil {
+		return d, err
+	}
+	if loaded.DirectoryName == "" {
+		loaded.DirectoryName = d.DirectoryName
+	}
+	pfs.dirs[d.DirectoryID] = loaded
+	return loaded, nil
+}
+
+func (pfs *PackageFS) entries(n fsNode) ([]fs.DirEntry, error) {
+	d, err := pfs.load(*n.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(d.SubDirectories)+len(d.Files))
+	for i := range d.SubDirectories {
+		sd := d.SubDirectories[i]
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{fsNode{name: sd.DirectoryName, dir: &sd}}))
+	}
+	for i := range d.Files {
+		f := d.Files[i]
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{fsNode{name: f.FileName, file: &f}}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func findNode(d Directory, name string) (fsNode, bool) {
+	for i := range d.SubDirectories {
+		if d.SubDirectories[i].DirectoryName == name {
+			sd := d.SubDirectories[i]
+			return fsNode{name: name, dir: &sd}, true
+		}
+	}
+	for i := range d.Files {
+		if d.Files[i].FileName == name {
+			f := d.Files[i]
+			return fsNode{name: name, file: &f}, true
+		}
+	}
+	return fsNode{}, false
+}
+
+type fileInfo struct {
+	n fsNode
+}
+
+func (fi fileInfo) Name() string {
+	return path.Base(fi.n.name)
+}
+
+func (fi fileInfo) Size() int64 {
+	if fi.n.file == nil {
+		return 0
+	}
+	return int64(fi.n.file.FileSizeInt())
+}
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.n.dir != nil {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi fileInfo) ModTime() time.Time {
+	if fi.n.file == nil {
+		return time.Time{}
+	}
+	return fi.n.file.FileUploadedTime()
+}
+
+func (fi fileInfo) IsDir() bool {
+	return fi.n.dir != nil
+}
+
+func (fi fileInfo) Sys() interface{} {
+	if fi.n.file != nil {
+		return *fi.n.file
+	}
+	return *fi.n.dir
+}
+
+type packageFile struct {
+	fs   *PackageFS
+	info fileInfo
+
+	offset   int64
+	part     int
+	r        io.ReadCloser
+	transfer *rate.Limiter
+}
+
+func (f *packageFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *packageFile) Read(b []byte) (int, error) {
+	file := f.info.n.file
+	for {
+		if f.r == nil {
+			if f.offset >= int64(file.FileSizeInt()) {
+				return 0, io.EOF
+			}
+			err := f.openAt(f.offset)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := f.r.Read(b)
+		f.offset += int64(n)
+		if err == io.EOF {
+			f.r.Close()
+			f.r = nil
+			err = f.fs.partEnded(*file, f.part, f.offset)
+			if err != nil {
+				return n, err
+			}
+			if n == 0 {
+				continue
+			}
+		}
+		return n, err
+	}
+}
+
+// openAt opens the part holding offset and discards up to it. Parts past
+// the last one whose start is known are read through whole, to learn
+// where the next one starts.
+func (f *packageFile) openAt(offset int64) error {
+	file := f.info.n.file
+	part, start := f.fs.locatePart(*file, offset)
+	for ; part <= file.Parts; part++ {
+		r, err := f.fs.api.openPart(f.fs.pm, f.fs.p, *file, part, f.transfer)
+		if err != nil {
+			return err
+		}
+		n, err := io.CopyN(ioutil.Discard, r, offset-start)
+		if err == nil {
+			f.r, f.part = r, part
+			return nil
+		}
+		r.Close()
+		if err != io.EOF {
+			return err
+		}
+
+		start += n
+		err = f.fs.partEnded(*file, part, start)
+		if err != nil {
+			return err
+		}
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// Seek only records the new offset. The part containing it is fetched on
+// the next Read, so seeking around a large file is cheap.
+func (f *packageFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(f.info.n.file.FileSizeInt())
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.info.n.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.n.name, Err: fs.ErrInvalid}
+	}
+
+	if offset != f.offset {
+		f.Close()
+		f.offset = offset
+	}
+	return offset, nil
+}
+
+func (f *packageFile) Close() error {
+	if f.r == nil {
+		return nil
+	}
+	err := f.r.Close()
+	f.r = nil
+	return err
+}
+
+// locatePart returns the last part known to start at or before offset,
+// and the offset it starts at.
+func (pfs *PackageFS) locatePart(f File, offset int64) (int, int64) {
+	pfs.mu.Lock()
+	defer pfs.mu.Unlock()
+
+	starts := pfs.starts[f.FileID]
+	part := sort.Search(len(starts), func(i int) bool { return starts[i] > offset })
+	if part == 0 {
+		return 1, 0
+	}
+	return part, starts[part-1]
+}
+
+// partEnded records that part of f was read through to end, so the next
+// part starts there. A part that decrypts to a different length than it
+// did before, or parts that don't add up to the file size, are an error
+// rather than a reason to serve shifted data.
+func (pfs *PackageFS) partEnded(f File, part int, end int64) error {
+	size := int64(f.FileSizeInt())
+	if end > size || (part == f.Parts && end != size) {
+		return fmt.Errorf("%s: Parts decrypt to a different size than the file's %d bytes", f.FileName, size)
+	}
+
+	pfs.mu.Lock()
+	defer pfs.mu.Unlock()
+
+	starts := pfs.starts[f.FileID]
+	if len(starts) == 0 {
+		starts = []int64{0}
+	}
+	switch {
+	case part < len(starts):
+		if starts[part] != end {
+			return fmt.Errorf("%s: Part %d decrypted to a different length than before", f.FileName, part)
+		}
+	case part == len(starts):
+		starts = append(starts, end)
+	}
+	pfs.starts[f.FileID] = starts
+	return nil
+}
+
+type packageDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *packageDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *packageDir) Read(b []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.n.name, Err: fs.ErrInvalid}
+}
+
+func (d *packageDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *packageDir) Close() error {
+	return nil
+}
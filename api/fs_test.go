@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testPackageFS() *PackageFS {
+	p := Package{
+		PackageID: "ABCD-EFGH",
+		Files: []File{
+			{FileID: "1", FileName: "logs.tgz", FileSize: "5242880", Parts: 2, FileUploaded: "Oct 31, 2018 6:22:37 PM"},
+			{FileID: "2", FileName: "notes.txt", FileSize: "12", Parts: 1},
+		},
+		Directories: []Directory{
+			{
+				DirectoryID:   "d1",
+				DirectoryName: "bundle",
+				Files: []File{
+					{FileID: "3", FileName: "heap.dump", FileSize: "1024", Parts: 1},
+				},
+				SubDirectories: []Directory{},
+			},
+		},
+	}
+	return NewPackageFS(NewAPI("host", "key", "secret"), PackageMetadata{}, p)
+}
+
+func TestPackageFSReadDir(t *testing.T) {
+	tables := []struct {
+		dir      string
+		expected []string
+	}{
+		{".", []string{"bundle/", "logs.tgz", "notes.txt"}},
+		{"bundle", []string{"heap.dump"}},
+	}
+
+	pfs := testPackageFS()
+
+	for _, table := range tables {
+		entries, err := fs.ReadDir(pfs, table.dir)
+		if err != nil {
+			t.Fatalf("ReadDir(%s) returned error: %s", table.dir, err)
+		}
+		names := []string{}
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		if len(names) != len(table.expected) {
+			t.Fatalf("ReadDir(%s) was incorrect, got: %v, want: %v.", table.dir, names, table.expected)
+		}
+		for i := range names {
+			if names[i] != table.expected[i] {
+				t.Errorf("ReadDir(%s) was incorrect, got: %v, want: %v.", table.dir, names, table.expected)
+			}
+		}
+	}
+}
+
+func TestPackageFSStat(t *testing.T) {
+	tables := []struct {
+		name    string
+		size    int64
+		modTime time.Time
+		isDir   bool
+		err     error
+	}{
+		{"logs.tgz", 5242880, time.Date(2018, 10, 31, 18, 22, 37, 0, time.UTC), false, nil},
+		{"bundle/heap.dump", 1024, time.Time{}, false, nil},
+		{"bundle", 0, time.Time{}, true, nil},
+		{"missing.txt", 0, time.Time{}, false, fs.ErrNotExist},
+		{"notes.txt/child", 0, time.Time{}, false, fs.ErrNotExist},
+		{"../escape", 0, time.Time{}, false, fs.ErrInvalid},
+	}
+
+	pfs := testPackageFS()
+
+	for _, table := range tables {
+		fi, err := fs.Stat(pfs, table.name)
+		if table.err != nil {
+			if !errors.Is(err, table.err) {
+				t.Errorf("Stat(%s) error was incorrect, got: %v, want: %v.", table.name, err, table.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Stat(%s) returned error: %s", table.name, err)
+		}
+		if fi.Size() != table.size || !fi.ModTime().Equal(table.modTime) || fi.IsDir() != table.isDir {
+			t.Errorf("Stat(%s) was incorrect, got: (%d, %s, %t), want: (%d, %s, %t).", table.name, fi.Size(), fi.ModTime(), fi.IsDir(), table.size, table.modTime, table.isDir)
+		}
+	}
+}
+
+// partServer serves the given parts of a file, encrypted as SendSafely
+// would.
+func partServer(t *testing.T, password []byte, parts [][]byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		json.NewDecoder(r.Body).Decode(&params)
+		part, _ := strconv.Atoi(params["part"])
+		if part < 1 || part > len(parts) {
+			http.NotFound(w, r)
+			return
+		}
+		if err := encryptPart(w, parts[part-1], password, "logs.tgz"); err != nil {
+			t.Error(err)
+		}
+	}))
+	return srv
+}
+
+func TestPackageFSSeek(t *testing.T) {
+	data := make([]byte, 2500)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	tables := []struct {
+		sizes  []int
+		offset int64
+		err    bool
+	}{
+		{[]int{1000, 1000, 500}, 1800, false},
+		{[]int{500, 1000, 1000}, 1800, false},
+		{[]int{250, 2250}, 1800, false},
+		{[]int{1000, 1000, 500}, 2495, false},
+		{[]int{500, 1000, 1000}, 0, false},
+		// Parts that add up to less than the file are an error, not
+		// shifted data.
+		{[]int{500, 1000, 900}, 1800, true},
+	}
+
+	for _, table := range tables {
+		var parts [][]byte
+		start := 0
+		for _, size := range table.sizes {
+			parts = append(parts, data[start:start+size])
+			start += size
+		}
+		pm := PackageMetadata{KeyCode: "keycode"}
+		p := Package{
+			PackageID:    "P1",
+			ServerSecret: "secret",
+			Files:        []File{{FileID: "F1", FileName: "logs.tgz", FileSize: "2500", Parts: len(parts)}},
+		}
+		srv := partServer(t, partPassword(p.ServerSecret, pm.KeyCode), parts)
+		pfs := NewPackageFS(NewAPI(srv.URL, "key", "secret"), pm, p)
+
+		// Seek twice, so the second seek uses the part offsets learned
+		// by the first.
+		for attempt := 0; attempt < 2; attempt++ {
+			fh, err := pfs.Open("logs.tgz")
+			if err != nil {
+				t.Fatalf("Open returned error: %s", err)
+			}
+			rs := fh.(io.ReadSeeker)
+			rs.Seek(table.offset, io.SeekStart)
+			b, err := ioutil.ReadAll(rs)
+			fh.Close()
+
+			if table.err {
+				if err == nil {
+					t.Errorf("Seek(%d) over parts %v should have failed.", table.offset, table.sizes)
+				}
+				break
+			}
+			if err != nil {
+				t.Errorf("Seek(%d) over parts %v returned error: %s", table.offset, table.sizes, err)
+				continue
+			}
+			if !bytes.Equal(b, data[table.offset:]) {
+				t.Errorf("Seek(%d) over parts %v read the wrong bytes, got %d bytes, want the last %d bytes of the file.", table.offset, table.sizes, len(b), 2500-table.offset)
+			}
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"strings"
+)
+
+type contactGroups struct {
+	ContactGroups []ContactGroup `json:"contactGroups"`
+}
+
+// GetContactGroups returns the caller's personal contact groups.
+func (a *API) GetContactGroups() ([]ContactGroup, error) {
+	var g contactGroups
+
+	err := a.requestJSON("/user/groups/", "GET", nil, &g)
+	if err != nil {
+		return nil, err
+	}
+	return g.ContactGroups, nil
+}
+
+// GetOrganizationContactGroups returns the contact groups shared across the
+// caller's organization. Accounts that aren't part of an enterprise have
+// none, rather than getting an error.
+func (a *API) GetOrganizationContactGroups() ([]ContactGroup, error) {
+	var g contactGroups
+
+	err := a.requestJSON("/enterprise/groups/", "GET", nil, &g)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Response == "DENIED" || apiErr.Response == "PERMISSION_DENIED") {
+		return []ContactGroup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range g.ContactGroups {
+		g.ContactGroups[i].ContactGroupIsOrganizationGroup = true
+	}
+	return g.ContactGroups, nil
+}
+
+// CreateContactGroup creates a group and returns its ID. Organization
+// groups can only be created by admin users.
+func (a *API) CreateContactGroup(name string, organization bool) (string, error) {
+	var g ContactGroup
+
+	postParams := make(map[string]interface{}, 2)
+	postParams["groupName"] = name
+	postParams["isEnterpriseGroup"] = organization
+
+	err := a.requestJSON("/contactgroup/", "PUT", postParams, &g)
+	if err != nil {
+		return "", err
+	}
+	return g.ContactGroupID, nil
+}
+
+func (a *API) DeleteContactGroup(groupID string) error {
+	path := "/contactgroup/" + groupID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+// AddContactGroupMember adds email to a group and returns the member's
+// user ID, which is needed to remove them again.
+func (a *API) AddContactGroupMember(groupID string, email string) (string, error) {
+	var u ContactGroupUser
+	path := "/contactgroup/" + groupID + "/email/"
+
+	postParams := make(map[string]string, 1)
+	postParams["userEmail"] = email
+
+	err := a.requestJSON(path, "PUT", postParams, &u)
+	if err != nil {
+		return "", err
+	}
+	return u.UserID, nil
+}
+
+func (a *API) RemoveContactGroupMember(groupID string, userID string) error {
+	path := "/contactgroup/" + groupID + "/email/" + userID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+// FindContactGroup matches a group by ID, or failing that by name.
+func FindContactGroup(groups []ContactGroup, nameOrID string) (ContactGroup, bool) {
+	for _, g := range groups {
+		if g.ContactGroupID == nameOrID {
+			return g, true
+		}
+	}
+	for _, g := range groups {
+		if strings.EqualFold(g.ContactGroupName, nameOrID) {
+			return g, true
+		}
+	}
+	return ContactGroup{}, false
+}
+
+// FindMember looks up a member of the group by email address.
+func (g *ContactGroup) FindMember(email string) (ContactGroupUser, bool) {
+	for _, u := range g.Users {
+		if strings.EqualFold(u.UserEmail, email) {
+			return u, true
+		}
+	}
+	return ContactGroupUser{}, false
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFindContactGroup(t *testing.T) {
+	groups := []ContactGroup{
+		{ContactGroupID: "g1", ContactGroupName: "Escalation Team"},
+		{ContactGroupID: "g2", ContactGroupName: "g1"},
+	}
+
+	tables := []struct {
+		nameOrID string
+		expected string
+		found    bool
+	}{
+		{"g1", "g1", true},
+		{"escalation team", "g1", true},
+		{"g2", "g2", true},
+		{"support", "", false},
+	}
+
+	for _, table := range tables {
+		g, found := FindContactGroup(groups, table.nameOrID)
+		if g.ContactGroupID != table.expected || found != table.found {
+			t.Errorf("FindContactGroup(%s) was incorrect, got: (%s, %t), want: (%s, %t).", table.nameOrID, g.ContactGroupID, found, table.expected, table.found)
+		}
+	}
+}
+
+func TestContactGroups(t *testing.T) {
+	tables := []struct {
+		status   int
+		body     string
+		expected []string
+		err      bool
+	}{
+		{200, `{"response":"SUCCESS","contactGroups":[{"contactGroupId":"g2","contactGroupName":"Support"}]}`, []string{"g1:false", "g2:true"}, false},
+		// Accounts outside an enterprise just have no organization groups.
+		{200, `{"response":"DENIED","message":"Not an enterprise account"}`, []string{"g1:false"}, false},
+		{200, `{"response":"PERMISSION_DENIED","message":"Not an enterprise account"}`, []string{"g1:false"}, false},
+		{200, `{"response":"AUTHENTICATION_FAILED","message":"Invalid API key"}`, nil, true},
+		{500, ``, nil, true},
+	}
+
+	for _, table := range tables {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/enterprise/groups/") {
+				w.WriteHeader(table.status)
+				w.Write([]byte(table.body))
+				return
+			}
+			w.Write([]byte(`{"response":"SUCCESS","contactGroups":[{"contactGroupId":"g1","contactGroupName":"Escalation Team"}]}`))
+		}))
+		a := NewAPI(srv.URL, "key", "secret")
+
+		groups, err := a.GetContactGroups()
+		if err != nil {
+			t.Fatalf("GetContactGroups returned error: %s", err)
+		}
+		org, err := a.GetOrganizationContactGroups()
+		srv.Close()
+		if (err != nil) != table.err {
+			t.Errorf("GetOrganizationContactGroups(%s) error was incorrect, got: %v, want error: %t.", table.body, err, table.err)
+			continue
+		}
+		if table.err {
+			continue
+		}
+
+		got := []string{}
+		for _, g := range append(groups, org...) {
+			got = append(got, fmt.Sprintf("%s:%t", g.ContactGroupID, g.ContactGroupIsOrganizationGroup))
+		}
+		if strings.Join(got, ",") != strings.Join(table.expected, ",") {
+			t.Errorf("Contact groups were incorrect, got: %v, want: %v.", got, table.expected)
+		}
+	}
+}
+
+func TestContactGroupMembers(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[strings.Index(r.URL.Path, "/contactgroup/"):]
+		requests = append(requests, r.Method+" "+path)
+		switch r.Method {
+		case "PUT":
+			w.Write([]byte(`{"response":"SUCCESS","contactGroupId":"g1","userId":"u1"}`))
+		default:
+			w.Write([]byte(`{"response":"SUCCESS"}`))
+		}
+	}))
+	defer srv.Close()
+	a := NewAPI(srv.URL, "key", "secret")
+
+	id, err := a.CreateContactGroup("Escalation Team", false)
+	if err != nil || id != "g1" {
+		t.Errorf("CreateContactGroup was incorrect, got: (%s, %v), want: g1.", id, err)
+	}
+	userID, err := a.AddContactGroupMember("g1", "oncall@test.com")
+	if err != nil || userID != "u1" {
+		t.Errorf("AddContactGroupMember was incorrect, got: (%s, %v), want: u1.", userID, err)
+	}
+	if err := a.RemoveContactGroupMember("g1", "u1"); err != nil {
+		t.Errorf("RemoveContactGroupMember returned error: %s", err)
+	}
+	if err := a.DeleteContactGroup("g1"); err != nil {
+		t.Errorf("DeleteContactGroup returned error: %s", err)
+	}
+
+	expected := []string{"PUT /contactgroup/", "PUT /contactgroup/g1/email/", "DELETE /contactgroup/g1/email/u1/", "DELETE /contactgroup/g1/"}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Requests were incorrect, got: %v, want: %v.", requests, expected)
+	}
+}
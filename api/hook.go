@@ -0,0 +1,106 @@
+package api
+
+import "fmt"
+
+// DownloadedFile is a file that has finished downloading to local disk.
+// Package has its ServerSecret cleared, so hooks never see it.
+type DownloadedFile struct {
+	Package Package `json:"package"`
+	File    File    `json:"file"`
+	Path    string  `json:"path"`
+	SHA256  string  `json:"sha256"`
+}
+
+// FileHook is run on each file once it has been downloaded and decrypted
+// to local disk, e.g. to scan it for malware. Hooks run in the order they
+// were added and the first to fail stops the rest.
+type FileHook interface {
+	Name() string
+	FileDownloaded(d DownloadedFile) error
+}
+
+// PackageHook is run once all the files being downloaded from a package
+// have finished, with those that downloaded successfully.
+type PackageHook interface {
+	Name() string
+	PackageDownloaded(p Package, files []DownloadedFile) error
+}
+
+func (a *API) AddFileHook(h FileHook) {
+	a.fileHooks = append(a.fileHooks, h)
+}
+
+func (a *API) AddPackageHook(h PackageHook) {
+	a.packageHooks = append(a.packageHooks, h)
+}
+
+// FileHookFunc adapts a function to a FileHook.
+func FileHookFunc(name string, fn func(d DownloadedFile) error) FileHook {
+	return hookFunc{name: name, file: fn}
+}
+
+// PackageHookFunc adapts a function to a PackageHook.
+func PackageHookFunc(name string, fn func(p Package, files []DownloadedFile) error) PackageHook {
+	return hookFunc{name: name, pkg: fn}
+}
+
+type hookFunc struct {
+	name string
+	file func(d DownloadedFile) error
+	pkg  func(p Package, files []DownloadedFile) error
+}
+
+func (h hookFunc) Name() string {
+	return h.name
+}
+
+func (h hookFunc) FileDownloaded(d DownloadedFile) error {
+	return h.file(d)
+}
+
+func (h hookFunc) PackageDownloaded(p Package, files []DownloadedFile) error {
+	return h.pkg(p, files)
+}
+
+// HookError is returned when a download succeeded but a hook run on it
+// afterwards failed. Use errors.As to tell it apart from a failed
+// download. Path is empty for package hooks.
+type HookError struct {
+	Hook string
+	Path string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+func (a *API) runFileHooks(d DownloadedFile) error {
+	d.Package.ServerSecret = ""
+	for _, h := range a.fileHooks {
+		err := h.FileDownloaded(d)
+		if err != nil {
+			return &HookError{Hook: h.Name(), Path: d.Path, Err: err}
+		}
+	}
+	return nil
+}
+
+// RunPackageHooks runs the package hooks once the files downloaded from p
+// have finished. Every hook runs, and the errors of those that failed are
+// returned as HookErrors.
+func (a *API) RunPackageHooks(p Package, files []DownloadedFile) []error {
+	p.ServerSecret = ""
+	var errs []error
+	for _, h := range a.packageHooks {
+		err := h.PackageDownloaded(p, files)
+		if err != nil {
+			errs = append(errs, &HookError{Hook: h.Name(), Err: err})
+		}
+	}
+	return errs
+}
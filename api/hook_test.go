@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+type testHook struct {
+	name string
+	err  error
+	seen *[]string
+}
+
+func (h testHook) Name() string {
+	return h.name
+}
+
+func (h testHook) FileDownloaded(d DownloadedFile) error {
+	*h.seen = append(*h.seen, h.name+":"+d.Path+":"+d.Package.ServerSecret)
+	return h.err
+}
+
+func TestRunFileHooks(t *testing.T) {
+	failed := errors.New("Infected with Eicar-Test-Signature")
+
+	tables := []struct {
+		errs     []error
+		expected []string
+		hook     string
+	}{
+		{[]error{nil, nil}, []string{"first:/tmp/logs.tgz:", "second:/tmp/logs.tgz:"}, ""},
+		{[]error{failed, nil}, []string{"first:/tmp/logs.tgz:"}, "first"},
+		{[]error{nil, failed}, []string{"first:/tmp/logs.tgz:", "second:/tmp/logs.tgz:"}, "second"},
+	}
+
+	for _, table := range tables {
+		seen := []string{}
+		a := NewAPI("host", "key", "secret")
+		a.AddFileHook(testHook{"first", table.errs[0], &seen})
+		a.AddFileHook(testHook{"second", table.errs[1], &seen})
+
+		err := a.runFileHooks(DownloadedFile{Package: Package{ServerSecret: "secret"}, Path: "/tmp/logs.tgz"})
+
+		var hookErr *HookError
+		if table.hook == "" && err != nil {
+			t.Errorf("runFileHooks returned error: %s", err)
+		}
+		if table.hook != "" && (!errors.As(err, &hookErr) || hookErr.Hook != table.hook || !errors.Is(err, failed)) {
+			t.Errorf("runFileHooks error was incorrect, got: %v, want a HookError from %s.", err, table.hook)
+		}
+		if len(seen) != len(table.expected) {
+			t.Fatalf("runFileHooks ran %v, want %v.", seen, table.expected)
+		}
+		for i := range seen {
+			if seen[i] != table.expected[i] {
+				t.Errorf("runFileHooks ran %v, want %v.", seen, table.expected)
+			}
+		}
+	}
+}
+
+func TestHookFuncs(t *testing.T) {
+	var got []string
+	fh := FileHookFunc("file", func(d DownloadedFile) error {
+		got = append(got, d.Path)
+		return nil
+	})
+	ph := PackageHookFunc("package", func(p Package, files []DownloadedFile) error {
+		got = append(got, p.PackageID+":"+p.ServerSecret)
+		return errors.New("Failed")
+	})
+
+	a := NewAPI("host", "key", "secret")
+	a.AddFileHook(fh)
+	a.AddPackageHook(ph)
+
+	err := a.runFileHooks(DownloadedFile{Path: "/tmp/logs.tgz"})
+	if err != nil {
+		t.Errorf("runFileHooks returned error: %s", err)
+	}
+	errs := a.RunPackageHooks(Package{PackageID: "ABCD-EFGH", ServerSecret: "secret"}, nil)
+	var hookErr *HookError
+	if len(errs) != 1 || !errors.As(errs[0], &hookErr) || hookErr.Hook != "package" {
+		t.Errorf("RunPackageHooks errors were incorrect, got: %v, want a HookError from package.", errs)
+	}
+
+	expected := []string{"/tmp/logs.tgz", "ABCD-EFGH:"}
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Hook funcs saw %v, want %v.", got, expected)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"io"
+	"time"
+)
+
+// Instrumentation receives events from an API as it works, for feeding
+// metrics or tracing systems. Implementations must be safe for concurrent
+// use. Paths have any secrets redacted.
+type Instrumentation interface {
+	RequestStarted(method string, path string)
+	RequestFinished(method string, path string, status int, duration time.Duration, err error)
+	// RequestRetried is called before a request is sent again, after
+	// waiting for wait.
+	RequestRetried(method string, path string, attempt int, wait time.Duration)
+	// PartDownloaded reports the encrypted bytes received for a part.
+	PartDownloaded(f File, part int, bytes int64)
+	// PartDecrypted reports the time spent decrypting a part, excluding
+	// time spent waiting on the network.
+	PartDecrypted(f File, part int, duration time.Duration)
+}
+
+func (a *API) SetInstrumentation(i Instrumentation) {
+	a.instrumentation = i
+}
+
+type noInstrumentation struct{}
+
+func (noInstrumentation) RequestStarted(method string, path string) {}
+func (noInstrumentation) RequestFinished(method string, path string, status int, duration time.Duration, err error) {
+}
+func (noInstrumentation) RequestRetried(method string, path string, attempt int, wait time.Duration) {
+}
+func (noInstrumentation) PartDownloaded(f File, part int, bytes int64)           {}
+func (noInstrumentation) PartDecrypted(f File, part int, duration time.Duration) {}
+
+func (a *API) instrument() Instrumentation {
+	if a.instrumentation == nil {
+		return noInstrumentation{}
+	}
+	return a.instrumentation
+}
+
+// timedReader counts the bytes read through it and the time spent waiting
+// on the underlying reader.
+type timedReader struct {
+	r       io.Reader
+	bytes   int64
+	elapsed time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.elapsed += time.Since(start)
+	t.bytes += int64(n)
+	return n, err
+}
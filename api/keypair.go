@@ -0,0 +1,219 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+var (
+	KeyPairBits         = 2048
+	PublicKeyIDHeader   = "SendSafely-Public-Key-Id"
+	privateKeyBlockType = "PGP PRIVATE KEY BLOCK"
+)
+
+// hashIDSHA256 is the OpenPGP algorithm ID for SHA-256 (RFC 4880 9.4).
+const hashIDSHA256 = 8
+
+// KeyPair is an OpenPGP key registered with SendSafely. Packages sent to
+// its owner have their keyCode encrypted to the public half, so they can
+// be downloaded without the secure link.
+type KeyPair struct {
+	PublicKeyID string
+	Entity      *openpgp.Entity
+}
+
+func GenerateKeyPair(name string, email string) (KeyPair, error) {
+	config := &packet.Config{
+		RSABits:       KeyPairBits,
+		DefaultCipher: encryptionConfig.DefaultCipher,
+		DefaultHash:   encryptionConfig.DefaultHash,
+	}
+
+	e, err := openpgp.NewEntity(name, "gosafely", email, config)
+	if err != nil {
+		return KeyPair{}, err
+	}
+
+	// NewEntity doesn't advertise any algorithm preferences, which leaves
+	// senders falling back to ones that aren't compiled in.
+	for _, id := range e.Identities {
+		id.SelfSignature.PreferredSymmetric = []uint8{uint8(packet.CipherAES256)}
+		id.SelfSignature.PreferredHash = []uint8{hashIDSHA256}
+		err = id.SelfSignature.SignUserId(id.UserId.Id, e.PrimaryKey, e.PrivateKey, config)
+		if err != nil {
+			return KeyPair{}, err
+		}
+	}
+
+	return KeyPair{Entity: e}, nil
+}
+
+// SaveKeyPair writes the armored private key to fp, recording the public
+// key ID in the armor headers. The file is only readable by its owner.
+func SaveKeyPair(kp KeyPair, fp string) error {
+	if _, err := os.Stat(fp); !os.IsNotExist(err) {
+		return fmt.Errorf("File exists")
+	}
+
+	var buf bytes.Buffer
+	headers := map[string]string{}
+	if kp.PublicKeyID != "" {
+		headers[PublicKeyIDHeader] = kp.PublicKeyID
+	}
+	w, err := armor.Encode(&buf, privateKeyBlockType, headers)
+	if err != nil {
+		return err
+	}
+	err = kp.Entity.SerializePrivate(w, nil)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fp, buf.Bytes(), 0600)
+}
+
+func LoadKeyPair(fp string) (KeyPair, error) {
+	var kp KeyPair
+
+	fh, err := os.Open(fp)
+	if err != nil {
+		return kp, err
+	}
+	defer fh.Close()
+
+	block, err := armor.Decode(fh)
+	if err != nil {
+		return kp, err
+	}
+	if block.Type != privateKeyBlockType {
+		return kp, fmt.Errorf("Expected %s, got %s", privateKeyBlockType, block.Type)
+	}
+
+	el, err := openpgp.ReadKeyRing(block.Body)
+	if err != nil {
+		return kp, err
+	}
+	if len(el) == 0 {
+		return kp, fmt.Errorf("No key found in %s", fp)
+	}
+
+	kp.Entity = el[0]
+	kp.PublicKeyID = block.Header[PublicKeyIDHeader]
+	return kp, nil
+}
+
+func (kp *KeyPair) ArmoredPublicKey() (string, error) {
+	var buf bytes.Buffer
+
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return "", err
+	}
+	err = kp.Entity.Serialize(w)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RegisterPublicKey uploads the public half of kp and sets its
+// PublicKeyID.
+func (a *API) RegisterPublicKey(kp *KeyPair, description string) error {
+	var r struct {
+		ID string `json:"id"`
+	}
+
+	pub, err := kp.ArmoredPublicKey()
+	if err != nil {
+		return err
+	}
+
+	postParams := make(map[string]string, 2)
+	postParams["publicKey"] = pub
+	postParams["description"] = description
+
+	err = a.requestJSON("/public-key/", "PUT", postParams, &r)
+	if err != nil {
+		return err
+	}
+
+	kp.PublicKeyID = r.ID
+	return nil
+}
+
+func (a *API) RevokePublicKey(publicKeyID string) error {
+	path := "/public-key/" + publicKeyID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+// GetKeyCode fetches the package keyCode that was encrypted to kp and
+// decrypts it.
+func (a *API) GetKeyCode(packageID string, kp KeyPair) (string, error) {
+	var m apiResponse
+	path := "/package/" + packageID + "/link/" + kp.PublicKeyID + "/"
+
+	if kp.PublicKeyID == "" {
+		return "", fmt.Errorf("Key pair has not been registered")
+	}
+
+	err := a.requestJSON(path, "GET", nil, &m)
+	if err != nil {
+		return "", err
+	}
+
+	return decryptKeyCode(m.Message, kp)
+}
+
+// GetPackageWithKeyPair is the key pair equivalent of reading the package
+// code and keyCode from a secure link.
+func (a *API) GetPackageWithKeyPair(packageID string, kp KeyPair) (Package, PackageMetadata, error) {
+	var pm PackageMetadata
+
+	p, err := a.GetPackage(packageID)
+	if err != nil {
+		return p, pm, err
+	}
+
+	keyCode, err := a.GetKeyCode(p.PackageID, kp)
+	if err != nil {
+		return p, pm, err
+	}
+
+	pm.Thread = p.PackageID
+	pm.PackageCode = p.PackageCode
+	pm.KeyCode = keyCode
+	return p, pm, nil
+}
+
+func decryptKeyCode(message string, kp KeyPair) (string, error) {
+	block, err := armor.Decode(strings.NewReader(message))
+	if err != nil {
+		return "", err
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{kp.Entity}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func TestKeyPair(t *testing.T) {
+	kp, err := GenerateKeyPair("Support Bot", "bot@test.com")
+	if err != nil {
+		t.Fatalf("GenerateKeyPair returned error: %s", err)
+	}
+	kp.PublicKeyID = "6e07a288-6382-4ca4-9931-adc972e32797"
+
+	fp := filepath.Join(t.TempDir(), "private.key")
+	err = SaveKeyPair(kp, fp)
+	if err != nil {
+		t.Fatalf("SaveKeyPair returned error: %s", err)
+	}
+	if SaveKeyPair(kp, fp) == nil {
+		t.Errorf("SaveKeyPair should not overwrite an existing key")
+	}
+
+	loaded, err := LoadKeyPair(fp)
+	if err != nil {
+		t.Fatalf("LoadKeyPair returned error: %s", err)
+	}
+	if loaded.PublicKeyID != kp.PublicKeyID {
+		t.Errorf("LoadKeyPair public key ID was incorrect, got: %s, want: %s.", loaded.PublicKeyID, kp.PublicKeyID)
+	}
+
+	keyCode := "aXaQiWhw9p29CAoDoLRxpWbzotX2Qe0D-0agiN_RYXU"
+
+	var buf bytes.Buffer
+	aw, _ := armor.Encode(&buf, "PGP MESSAGE", nil)
+	w, err := openpgp.Encrypt(aw, openpgp.EntityList{kp.Entity}, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Encrypt returned error: %s", err)
+	}
+	w.Write([]byte(keyCode))
+	w.Close()
+	aw.Close()
+
+	result, err := decryptKeyCode(buf.String(), loaded)
+	if err != nil {
+		t.Fatalf("decryptKeyCode returned error: %s", err)
+	}
+	if result != keyCode {
+		t.Errorf("decryptKeyCode was incorrect, got: %s, want: %s.", result, keyCode)
+	}
+}
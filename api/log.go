@@ -0,0 +1,35 @@
+package api
+
+import (
+	"regexp"
+)
+
+var (
+	redacted = "[REDACTED]"
+
+	// Values of these JSON fields are secrets or derived from the keyCode.
+	redactFieldsRe = regexp.MustCompile(`("(?:checksum|keyCode|message|password|serverSecret|publicKey)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// Checksums also turn up as path segments, e.g. in message requests.
+	redactPathRe = regexp.MustCompile(`[0-9a-fA-F]{64}`)
+	partRe       = regexp.MustCompile(`"part"\s*:\s*"?(\d+)`)
+)
+
+// redactBody hides secrets in a request body without otherwise changing
+// it, so the logged string still lines up with what was signed.
+func redactBody(data []byte) string {
+	return redactFieldsRe.ReplaceAllString(string(data), `$1"`+redacted+`"`)
+}
+
+func redactPath(path string) string {
+	return redactPathRe.ReplaceAllString(path, redacted)
+}
+
+// requestPart returns the part number a download or upload request is
+// for, or an empty string.
+func requestPart(data []byte) string {
+	m := partRe.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return string(m[1])
+}
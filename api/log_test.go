@@ -0,0 +1,57 @@
+package api
+
+import "testing"
+
+func TestRedactBody(t *testing.T) {
+	tables := []struct {
+		data     string
+		expected string
+	}{
+		{`{"part":"1","checksum":"298dc53a5ce68159ff848b9b1c8674561a70c3594cdb05d3baa807e4f7a6f10b","api":"JAVA_API"}`, `{"part":"1","checksum":"[REDACTED]","api":"JAVA_API"}`},
+		{`{"message": "-----BEGIN PGP MESSAGE-----\n\"quoted\"\n"}`, `{"message": "[REDACTED]"}`},
+		{`{"email":"user1@test.com"}`, `{"email":"user1@test.com"}`},
+		{``, ``},
+	}
+
+	for _, table := range tables {
+		result := redactBody([]byte(table.data))
+		if result != table.expected {
+			t.Errorf("redactBody of %s was incorrect, got: %s, want: %s.", table.data, result, table.expected)
+		}
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	tables := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/v2.0/package/ABCD-EFGH/message/298dc53a5ce68159ff848b9b1c8674561a70c3594cdb05d3baa807e4f7a6f10b/", "/api/v2.0/package/ABCD-EFGH/message/[REDACTED]/"},
+		{"/api/v2.0/package/ABCD-EFGH/file/6e07a288-6382-4ca4-9931-adc972e32797/download/", "/api/v2.0/package/ABCD-EFGH/file/6e07a288-6382-4ca4-9931-adc972e32797/download/"},
+	}
+
+	for _, table := range tables {
+		result := redactPath(table.path)
+		if result != table.expected {
+			t.Errorf("redactPath of %s was incorrect, got: %s, want: %s.", table.path, result, table.expected)
+		}
+	}
+}
+
+func TestRequestPart(t *testing.T) {
+	tables := []struct {
+		data     string
+		expected string
+	}{
+		{`{"part":"12","checksum":"abc"}`, "12"},
+		{`{"part": 3}`, "3"},
+		{`{"email":"user1@test.com"}`, ""},
+	}
+
+	for _, table := range tables {
+		result := requestPart([]byte(table.data))
+		if result != table.expected {
+			t.Errorf("requestPart of %s was incorrect, got: %s, want: %s.", table.data, result, table.expected)
+		}
+	}
+}
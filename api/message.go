@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+var encryptionConfig = &packet.Config{
+	DefaultCipher:          packet.CipherAES256,
+	DefaultHash:            crypto.SHA256,
+	DefaultCompressionAlgo: packet.CompressionNone,
+}
+
+// GetPackageMessage returns the decrypted message the sender attached to
+// the package, or an empty string if there isn't one.
+func (a *API) GetPackageMessage(pm PackageMetadata, p Package) (string, error) {
+	var m apiResponse
+	path := "/package/" + p.PackageID + "/message/" + createChecksum(pm.KeyCode, p.PackageCode) + "/"
+
+	err := a.requestJSON(path, "GET", nil, &m)
+	if err != nil {
+		return "", err
+	}
+	if m.Message == "" {
+		return "", nil
+	}
+
+	return decryptArmored(m.Message, []byte(p.ServerSecret+pm.KeyCode))
+}
+
+// SavePackageMessage encrypts message with the package keys and attaches
+// it to a package that has not been finalized yet.
+func (a *API) SavePackageMessage(pm PackageMetadata, p Package, message string) error {
+	path := "/package/" + p.PackageID + "/message/"
+
+	encrypted, err := encryptArmored(message, []byte(p.ServerSecret+pm.KeyCode))
+	if err != nil {
+		return err
+	}
+
+	postParams := make(map[string]string, 1)
+	postParams["message"] = encrypted
+
+	return a.requestJSON(path, "PUT", postParams, nil)
+}
+
+func encryptArmored(message string, password []byte) (string, error) {
+	var buf bytes.Buffer
+
+	aw, err := armor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", err
+	}
+
+	w, err := openpgp.SymmetricallyEncrypt(aw, password, nil, encryptionConfig)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write([]byte(message))
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	err = aw.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func decryptArmored(message string, password []byte) (string, error) {
+	block, err := armor.Decode(strings.NewReader(message))
+	if err != nil {
+		return "", err
+	}
+
+	md, err := decryptMessage(block.Body, password)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptArmored(t *testing.T) {
+	tables := []struct {
+		message  string
+		password string
+	}{
+		{"Logs from the failing node are attached.", "serverSecretkeyCode"},
+		{"", "serverSecretkeyCode"},
+		{"multi\nline\nmessage", "aXaQiWhw9p29CAoDoLRxpWbzotX2Qe0D-0agiN_RYXU"},
+	}
+
+	for _, table := range tables {
+		encrypted, err := encryptArmored(table.message, []byte(table.password))
+		if err != nil {
+			t.Fatalf("encryptArmored returned error: %s", err)
+		}
+		if !strings.HasPrefix(encrypted, "-----BEGIN PGP MESSAGE-----") {
+			t.Errorf("encryptArmored did not armor the message, got: %s", encrypted)
+		}
+
+		result, err := decryptArmored(encrypted, []byte(table.password))
+		if err != nil {
+			t.Fatalf("decryptArmored returned error: %s", err)
+		}
+		if result != table.message {
+			t.Errorf("decryptArmored was incorrect, got: %s, want: %s.", result, table.message)
+		}
+
+		_, err = decryptArmored(encrypted, []byte("wrong"+table.password))
+		if err == nil {
+			t.Errorf("decryptArmored with the wrong password should fail")
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// EncryptedPartName is the name SaveEncryptedParts gives the file holding
+// a part of f. The file name is chosen by the sender, so only its base is
+// used.
+func EncryptedPartName(f File, part int) string {
+	return fmt.Sprintf("%s.part%d", baseName(f.FileName), part)
+}
+
+// baseName strips any directories from a name chosen by the sender,
+// whichever separator they used, so it can't be used to write outside
+// the directory it is joined to.
+func baseName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// FetchPart returns a reader over a part of a file exactly as the server
+// sent it, still encrypted. Closing it releases the response body.
+func (a *API) FetchPart(pm PackageMetadata, p Package, f File, part int) (io.ReadCloser, error) {
+	r, err := a.fetchPart(pm, p, f, part)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedPart{Reader: r, body: r}, nil
+}
+
+// SaveEncryptedParts fetches every part of f into dir without decrypting
+// them and returns the paths written, in part order. DecryptParts turns
+// them back into the original file, with no network access needed.
+func (a *API) SaveEncryptedParts(pm PackageMetadata, p Package, f File, dir string, progress ProgressReporter) ([]string, error) {
+	parts, err := a.saveParts(pm, p, f, dir, progress)
+	paths := make([]string, len(parts))
+	for i := range parts {
+		paths[i] = parts[i].Path
+	}
+	return paths, err
+}
+
+func (a *API) saveParts(pm PackageMetadata, p Package, f File, dir string, progress ProgressReporter) (parts []ArchivePart, err error) {
+	tracker := newProgressTracker(progress, f, f.FileSizeInt())
+	tracker.report(FileStarted, nil)
+	defer func() {
+		tracker.report(FileFinished, err)
+	}()
+
+	transfer := a.newTransferLimiter()
+	for i := 1; i <= f.Parts; i++ {
+		tracker.startPart(i)
+		fp := filepath.Join(dir, EncryptedPartName(f, i))
+		part, err := a.savePart(pm, p, f, i, fp, tracker, transfer)
+		if err != nil {
+			return parts, err
+		}
+		parts = append(parts, part)
+		tracker.report(PartFinished, nil)
+	}
+	return parts, nil
+}
+
+// savePart writes a part to fp exactly as it is received, hashing it on
+// the way so the hash covers what came off the wire.
+func (a *API) savePart(pm PackageMetadata, p Package, f File, part int, fp string, progress io.Writer, transfer *rate.Limiter) (ArchivePart, error) {
+	ap := ArchivePart{Part: part, Path: fp}
+
+	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return ap, fmt.Errorf("File exists")
+	}
+	if err != nil {
+		return ap, err
+	}
+	defer fh.Close()
+
+	r, err := a.fetchPart(pm, p, f, part)
+	if err != nil {
+		return ap, err
+	}
+	defer closeReader(r)
+
+	h := sha256.New()
+	body := &timedReader{r: a.limitReader(r, transfer)}
+	ap.Size, err = io.Copy(io.MultiWriter(fh, h, progress), body)
+	a.instrument().PartDownloaded(f, part, body.bytes)
+	if err != nil {
+		return ap, err
+	}
+	ap.SHA256 = hex.EncodeToString(h.Sum(nil))
+	ap.Fetched = time.Now().UTC()
+	return ap, fh.Close()
+}
+
+// ServerSecretFile is the file in a directory of saved parts that
+// WriteServerSecret keeps the package's server secret in.
+const ServerSecretFile = "server-secret"
+
+// WriteServerSecret saves the server secret next to the parts in dir,
+// readable only by the user, so it never has to be printed.
+func WriteServerSecret(dir string, serverSecret string) error {
+	fp := filepath.Join(dir, ServerSecretFile)
+	err := ioutil.WriteFile(fp, []byte(serverSecret+"\n"), 0600)
+	if err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a file that already exists.
+	return os.Chmod(fp, 0600)
+}
+
+// ReadServerSecret returns the server secret saved in dir by
+// WriteServerSecret.
+func ReadServerSecret(dir string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ServerSecretFile))
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return "", fmt.Errorf("No server secret in %s", filepath.Join(dir, ServerSecretFile))
+	}
+	return secret, nil
+}
+
+// DecryptParts decrypts part files saved by SaveEncryptedParts, in the
+// order given, into a new file at fp. Only the server secret and keyCode
+// are needed to decrypt them. If any part can't be decrypted fp is
+// removed, so a partial file is never left behind.
+func DecryptParts(serverSecret string, keyCode string, parts []string, fp string) (err error) {
+	if _, err := os.Stat(fp); !os.IsNotExist(err) {
+		return fmt.Errorf("File exists")
+	}
+	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fh.Close()
+			os.Remove(fp)
+		}
+	}()
+
+	password := partPassword(serverSecret, keyCode)
+	for _, part := range parts {
+		err = decryptPartFile(fh, part, password)
+		if err != nil {
+			return fmt.Errorf("%s: %s", part, err)
+		}
+	}
+	return fh.Close()
+}
+
+func decryptPartFile(w io.Writer, part string, password []byte) error {
+	fh, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	md, err := decryptMessage(fh, password)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, md.UnverifiedBody)
+	return err
+}
+
+type encryptedPart struct {
+	io.Reader
+	body io.Reader
+}
+
+func (p *encryptedPart) Close() error {
+	return closeReader(p.body)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeEncryptedParts(t *testing.T, dir string, f File, password []byte, data [][]byte) []string {
+	parts := []string{}
+	for i, d := range data {
+		var buf bytes.Buffer
+		err := encryptPart(&buf, d, password, f.FileName)
+		if err != nil {
+			t.Fatalf("encryptPart returned error: %s", err)
+		}
+		fp := filepath.Join(dir, EncryptedPartName(f, i+1))
+		err = ioutil.WriteFile(fp, buf.Bytes(), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, fp)
+	}
+	return parts
+}
+
+func TestDecryptParts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := File{FileName: "logs.tgz"}
+	parts := writeEncryptedParts(t, dir, f, partPassword("secret", "keycode"), [][]byte{
+		[]byte("first part,"),
+		[]byte("second part"),
+	})
+
+	tables := []struct {
+		serverSecret string
+		keyCode      string
+		output       string
+		expected     string
+		err          bool
+	}{
+		{"secret", "keycode", "good.tgz", "first part,second part", false},
+		{"secret", "wrong", "bad.tgz", "", true},
+		// The failed attempt must not leave bad.tgz behind.
+		{"secret", "keycode", "bad.tgz", "first part,second part", false},
+		{"secret", "keycode", "good.tgz", "", true},
+	}
+
+	for _, table := range tables {
+		fp := filepath.Join(dir, table.output)
+		err := DecryptParts(table.serverSecret, table.keyCode, parts, fp)
+		if table.err {
+			if err == nil {
+				t.Errorf("DecryptParts(%s, %s, %s) should have failed.", table.serverSecret, table.keyCode, table.output)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("DecryptParts returned error: %s", err)
+		}
+		b, err := ioutil.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != table.expected {
+			t.Errorf("DecryptParts was incorrect, got: %q, want: %q.", b, table.expected)
+		}
+	}
+}
+
+func TestServerSecretFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = ReadServerSecret(dir)
+	if err == nil {
+		t.Errorf("ReadServerSecret should have failed without a secret file.")
+	}
+
+	// An existing file, however it was created, must end up private.
+	fp := filepath.Join(dir, ServerSecretFile)
+	err = ioutil.WriteFile(fp, []byte("old"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteServerSecret(dir, "secret")
+	if err != nil {
+		t.Fatalf("WriteServerSecret returned error: %s", err)
+	}
+	fi, err := os.Stat(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("Server secret file mode was incorrect, got: %o, want: %o.", fi.Mode().Perm(), 0600)
+	}
+
+	secret, err := ReadServerSecret(dir)
+	if err != nil {
+		t.Fatalf("ReadServerSecret returned error: %s", err)
+	}
+	if secret != "secret" {
+		t.Errorf("ReadServerSecret was incorrect, got: %q, want: %q.", secret, "secret")
+	}
+}
+
+func TestEncryptedPartName(t *testing.T) {
+	tables := []struct {
+		name     string
+		part     int
+		expected string
+	}{
+		{"logs.tgz", 1, "logs.tgz.part1"},
+		{"heap.dump", 12, "heap.dump.part12"},
+		{"../../.bashrc", 1, ".bashrc.part1"},
+		{`..\..\evil.bat`, 2, "evil.bat.part2"},
+		{"..", 1, "file.part1"},
+	}
+
+	for _, table := range tables {
+		result := EncryptedPartName(File{FileName: table.name}, table.part)
+		if result != table.expected {
+			t.Errorf("EncryptedPartName of (%s, %d) was incorrect, got: %s, want: %s.", table.name, table.part, result, table.expected)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	PackZip   = "zip"
+	PackTarGz = "tgz"
+)
+
+// PackOptions controls how PackDirectory archives a directory. Patterns
+// use path.Match syntax and are matched against both the slash separated
+// path relative to the directory and the base name, so "*.log" matches
+// log files anywhere. Excluding a directory skips everything under it.
+type PackOptions struct {
+	Format  string
+	Include []string
+	Exclude []string
+}
+
+// PackName is the name of the archive PackDirectory makes of dir.
+func PackName(dir string, opts PackOptions) string {
+	name := filepath.Base(filepath.Clean(dir))
+	if opts.Format == PackTarGz {
+		return name + ".tar.gz"
+	}
+	return name + ".zip"
+}
+
+// PackDirectory archives dir as it is read, so the archive never has to
+// be written to disk. Only regular files are archived; symlinks are not
+// followed. Errors walking dir are returned from Read.
+func PackDirectory(dir string, opts PackOptions) (io.ReadCloser, error) {
+	if opts.Format != PackZip && opts.Format != PackTarGz {
+		return nil, fmt.Errorf("Unknown archive format %s", opts.Format)
+	}
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern %s", pattern)
+		}
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(packDirectory(pw, dir, opts))
+	}()
+	return pr, nil
+}
+
+func packDirectory(w io.Writer, dir string, opts PackOptions) error {
+	var add func(rel string, fi fs.FileInfo, r io.Reader) error
+	var closers []io.Closer
+
+	if opts.Format == PackTarGz {
+		gw := gzip.NewWriter(w)
+		tw := tar.NewWriter(gw)
+		closers = append(closers, tw, gw)
+		add = func(rel string, fi fs.FileInfo, r io.Reader) error {
+			hdr, err := tar.FileInfoHeader(fi, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			err = tw.WriteHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, r)
+			return err
+		}
+	} else {
+		zw := zip.NewWriter(w)
+		closers = append(closers, zw)
+		add = func(rel string, fi fs.FileInfo, r io.Reader) error {
+			hdr, err := zip.FileInfoHeader(fi)
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			hdr.Method = zip.Deflate
+			fw, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fw, r)
+			return err
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, fp)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			if matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !opts.included(rel) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fh, err := os.Open(fp)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		return add(rel, fi, fh)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range closers {
+		err = c.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o PackOptions) included(rel string) bool {
+	if matchAny(o.Exclude, rel) {
+		return false
+	}
+	return len(o.Include) == 0 || matchAny(o.Include, rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadDirectory archives dir and uploads the archive as it is made.
+func (a *API) UploadDirectory(pm PackageMetadata, p Package, dir string, opts PackOptions, progress ProgressReporter) (File, error) {
+	r, err := PackDirectory(dir, opts)
+	if err != nil {
+		return File{}, err
+	}
+	defer r.Close()
+	return a.UploadReader(pm, p, PackName(dir, opts), r, -1, progress)
+}
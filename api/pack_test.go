@@ -0,0 +1,105 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func packedNames(t *testing.T, format string, b []byte) []string {
+	names := []string{}
+	if format == PackZip {
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+	} else {
+		gr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, hdr.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPackDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"case/notes.txt", "case/logs/app.log", "case/logs/db.log", "case/.git/config", "case/heap.dump"} {
+		fp := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(fp), 0755)
+		if err := ioutil.WriteFile(fp, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tables := []struct {
+		opts     PackOptions
+		expected []string
+	}{
+		{PackOptions{Format: PackZip}, []string{".git/config", "heap.dump", "logs/app.log", "logs/db.log", "notes.txt"}},
+		{PackOptions{Format: PackTarGz, Exclude: []string{".git"}}, []string{"heap.dump", "logs/app.log", "logs/db.log", "notes.txt"}},
+		{PackOptions{Format: PackZip, Include: []string{"*.log"}}, []string{"logs/app.log", "logs/db.log"}},
+		{PackOptions{Format: PackTarGz, Include: []string{"*.log", "notes.txt"}, Exclude: []string{"logs/db.log"}}, []string{"logs/app.log", "notes.txt"}},
+	}
+
+	for _, table := range tables {
+		r, err := PackDirectory(filepath.Join(dir, "case"), table.opts)
+		if err != nil {
+			t.Fatalf("PackDirectory returned error: %s", err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("Reading packed directory returned error: %s", err)
+		}
+		names := packedNames(t, table.opts.Format, b)
+		if strings.Join(names, ",") != strings.Join(table.expected, ",") {
+			t.Errorf("PackDirectory(%+v) was incorrect, got: %v, want: %v.", table.opts, names, table.expected)
+		}
+	}
+}
+
+func TestPackName(t *testing.T) {
+	tables := []struct {
+		dir      string
+		format   string
+		expected string
+	}{
+		{"case-1234", PackZip, "case-1234.zip"},
+		{"/tmp/case-1234/", PackTarGz, "case-1234.tar.gz"},
+	}
+
+	for _, table := range tables {
+		result := PackName(table.dir, PackOptions{Format: table.format})
+		if result != table.expected {
+			t.Errorf("PackName of (%s, %s) was incorrect, got: %s, want: %s.", table.dir, table.format, result, table.expected)
+		}
+	}
+}
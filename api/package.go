@@ -0,0 +1,43 @@
+package api
+
+// UpdatePackageLife sets the number of days a package is available for,
+// counted from when it was sent. Zero means the package never expires.
+func (a *API) UpdatePackageLife(packageID string, days int) error {
+	path := "/package/" + packageID + "/"
+
+	postParams := make(map[string]int, 1)
+	postParams["life"] = days
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+func (a *API) UpdatePackageLabel(packageID string, label string) error {
+	path := "/package/" + packageID + "/"
+
+	postParams := make(map[string]string, 1)
+	postParams["label"] = label
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+// ExpirePackage makes a package unavailable to recipients immediately. The
+// package stays in the sender's history, unlike with DeletePackage.
+func (a *API) ExpirePackage(packageID string) error {
+	path := "/package/" + packageID + "/expire/"
+	return a.requestJSON(path, "POST", nil, nil)
+}
+
+func (a *API) DeletePackage(packageID string) error {
+	path := "/package/" + packageID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+func (a *API) ArchivePackage(packageID string) error {
+	path := "/package/" + packageID + "/archive/"
+	return a.requestJSON(path, "POST", nil, nil)
+}
+
+func (a *API) UnarchivePackage(packageID string) error {
+	path := "/package/" + packageID + "/unarchive/"
+	return a.requestJSON(path, "POST", nil, nil)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPackageLifecycle(t *testing.T) {
+	var method, path string
+	var params map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = nil
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &params)
+		method = r.Method
+		path = r.URL.Path[strings.Index(r.URL.Path, "/package/"):]
+
+		if strings.Contains(path, "/LOCKED/") {
+			w.Write([]byte(`{"response":"FAIL","message":"Package is locked"}`))
+			return
+		}
+		w.Write([]byte(`{"response":"SUCCESS"}`))
+	}))
+	defer srv.Close()
+	a := NewAPI(srv.URL, "key", "secret")
+
+	tables := []struct {
+		name   string
+		call   func() error
+		method string
+		path   string
+		params map[string]interface{}
+		err    string
+	}{
+		{"UpdatePackageLife", func() error { return a.UpdatePackageLife("P1", 30) }, "POST", "/package/P1/", map[string]interface{}{"life": float64(30)}, ""},
+		{"UpdatePackageLife", func() error { return a.UpdatePackageLife("P1", 0) }, "POST", "/package/P1/", map[string]interface{}{"life": float64(0)}, ""},
+		{"UpdatePackageLabel", func() error { return a.UpdatePackageLabel("P1", "Case 1234") }, "POST", "/package/P1/", map[string]interface{}{"label": "Case 1234"}, ""},
+		{"ExpirePackage", func() error { return a.ExpirePackage("P1") }, "POST", "/package/P1/expire/", nil, ""},
+		{"DeletePackage", func() error { return a.DeletePackage("P1") }, "DELETE", "/package/P1/", nil, ""},
+		{"ArchivePackage", func() error { return a.ArchivePackage("P1") }, "POST", "/package/P1/archive/", nil, ""},
+		{"UnarchivePackage", func() error { return a.UnarchivePackage("P1") }, "POST", "/package/P1/unarchive/", nil, ""},
+		{"ExpirePackage", func() error { return a.ExpirePackage("LOCKED") }, "POST", "/package/LOCKED/expire/", nil, "Got API response FAIL: Package is locked"},
+	}
+
+	for _, table := range tables {
+		err := table.call()
+		if table.err == "" && err != nil {
+			t.Errorf("%s returned error: %s", table.name, err)
+		}
+		if table.err != "" && (err == nil || err.Error() != table.err) {
+			t.Errorf("%s error was incorrect, got: %v, want: %s.", table.name, err, table.err)
+		}
+
+		if method != table.method || path != table.path {
+			t.Errorf("%s request was incorrect, got: %s %s, want: %s %s.", table.name, method, path, table.method, table.path)
+		}
+		if len(params) != len(table.params) {
+			t.Errorf("%s parameters were incorrect, got: %v, want: %v.", table.name, params, table.params)
+			continue
+		}
+		for k, v := range table.params {
+			if params[k] != v {
+				t.Errorf("%s parameters were incorrect, got: %v, want: %v.", table.name, params, table.params)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// ErrFreeSpaceUnsupported is returned by FreeSpace on platforms where it
+// can't be found. Preflight skips the free space check on them.
+var ErrFreeSpaceUnsupported = errors.New("Free space check not supported on this platform")
+
+type PreflightOptions struct {
+	// Margin is how much space must be left free once the files have been
+	// downloaded.
+	Margin int64
+	// MaxFileSize rejects any file larger than it, if not zero.
+	MaxFileSize int64
+}
+
+// freeSpace is swapped out in tests.
+var freeSpace = FreeSpace
+
+// Preflight checks files can be downloaded into dir before any are
+// fetched: none is larger than the maximum size, and together they fit in
+// the space free on dir's filesystem, leaving the margin spare. An empty
+// dir only checks file sizes, for destinations that aren't on local disk.
+func Preflight(files []File, dir string, opts PreflightOptions) error {
+	var total uint64
+	for _, f := range files {
+		size := f.FileSizeInt()
+		if opts.MaxFileSize > 0 && size > uint64(opts.MaxFileSize) {
+			return fmt.Errorf("%s is %s, larger than the maximum of %s", f.FileName, humanize.IBytes(size), humanize.IBytes(uint64(opts.MaxFileSize)))
+		}
+		total += size
+	}
+	if dir == "" {
+		return nil
+	}
+
+	free, err := freeSpace(dir)
+	if err == ErrFreeSpaceUnsupported {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	margin := uint64(opts.Margin)
+	if total+margin > free {
+		return fmt.Errorf("Not enough space in %s: need %s plus a %s margin, %s free", dir, humanize.IBytes(total), humanize.IBytes(margin), humanize.IBytes(free))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	defer func(f func(string) (uint64, error)) { freeSpace = f }(freeSpace)
+	freeSpace = func(dir string) (uint64, error) {
+		return 10 * 1024 * 1024, nil
+	}
+
+	files := []File{
+		{FileName: "logs.tgz", FileSize: "4194304"},
+		{FileName: "heap.dump", FileSize: "5242880"},
+	}
+
+	tables := []struct {
+		files []File
+		dir   string
+		opts  PreflightOptions
+		err   string
+	}{
+		{files, "/data", PreflightOptions{}, ""},
+		{files, "/data", PreflightOptions{Margin: 1024 * 1024}, ""},
+		{files, "/data", PreflightOptions{Margin: 2 * 1024 * 1024}, "Not enough space in /data: need 9.0 MiB plus a 2.0 MiB margin, 10 MiB free"},
+		{files, "", PreflightOptions{Margin: 2 * 1024 * 1024}, ""},
+		{files, "", PreflightOptions{MaxFileSize: 4 * 1024 * 1024}, "heap.dump is 5.0 MiB, larger than the maximum of 4.0 MiB"},
+		{files[:1], "/data", PreflightOptions{Margin: 2 * 1024 * 1024, MaxFileSize: 4 * 1024 * 1024}, ""},
+	}
+
+	for _, table := range tables {
+		err := Preflight(table.files, table.dir, table.opts)
+		errString := ""
+		if err != nil {
+			errString = err.Error()
+		}
+		if errString != table.err {
+			t.Errorf("Preflight(%+v) was incorrect, got: %q, want: %q.", table.opts, errString, table.err)
+		}
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace(os.TempDir())
+	if err == ErrFreeSpaceUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("FreeSpace returned error: %s", err)
+	}
+	if free == 0 {
+		t.Errorf("FreeSpace of %s was 0", os.TempDir())
+	}
+
+	_, err = FreeSpace("/does/not/exist")
+	if err == nil {
+		t.Errorf("FreeSpace of a missing directory should fail, got: %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+type ProgressEventType int
+
+const (
+	FileStarted ProgressEventType = iota
+	PartStarted
+	BytesTransferred
+	PartFinished
+	// FileFinished is sent when a transfer ends, successfully or not. Err
+	// is set if it failed.
+	FileFinished
+)
+
+func (t ProgressEventType) String() string {
+	switch t {
+	case FileStarted:
+		return "file started"
+	case PartStarted:
+		return "part started"
+	case BytesTransferred:
+		return "bytes transferred"
+	case PartFinished:
+		return "part finished"
+	case FileFinished:
+		return "file finished"
+	}
+	return fmt.Sprintf("ProgressEventType(%d)", int(t))
+}
+
+// ProgressEvent describes how far a file transfer has got. Rate is in
+// bytes per second averaged over the transfer so far, and ETA is zero
+// until there is a rate to estimate from.
+type ProgressEvent struct {
+	Type    ProgressEventType
+	File    File
+	Part    int
+	Parts   int
+	Current uint64
+	Total   uint64
+	Rate    float64
+	ETA     time.Duration
+	Elapsed time.Duration
+	Err     error
+}
+
+// ProgressReporter receives events from DownloadFile and UploadFile. A
+// reporter shared by concurrent transfers must be safe for concurrent use.
+type ProgressReporter interface {
+	Report(ev ProgressEvent)
+}
+
+// ProgressFunc adapts a function to a ProgressReporter.
+type ProgressFunc func(ev ProgressEvent)
+
+func (f ProgressFunc) Report(ev ProgressEvent) {
+	f(ev)
+}
+
+// BytesProgress adapts a func(current, total) to a ProgressReporter, for
+// callers that only care about bytes transferred.
+type BytesProgress func(current uint64, total uint64)
+
+func (f BytesProgress) Report(ev ProgressEvent) {
+	if ev.Type == BytesTransferred {
+		f(ev.Current, ev.Total)
+	}
+}
+
+func ProgressPrintBytes(current uint64, total uint64) {
+	fmt.Printf("\r%s", strings.Repeat(" ", 35))
+	fmt.Printf("\r%s/%s", humanize.Bytes(current), humanize.Bytes(total))
+}
+
+func ProgressNone(current uint64, total uint64) {}
+
+// progressTracker turns writes into progress events for one file.
+type progressTracker struct {
+	reporter ProgressReporter
+	file     File
+	total    uint64
+	current  uint64
+	part     int
+	start    time.Time
+}
+
+func newProgressTracker(reporter ProgressReporter, f File, total uint64) *progressTracker {
+	if reporter == nil {
+		reporter = BytesProgress(ProgressNone)
+	}
+	return &progressTracker{
+		reporter: reporter,
+		file:     f,
+		total:    total,
+		start:    time.Now(),
+	}
+}
+
+func (t *progressTracker) Write(p []byte) (int, error) {
+	t.current += uint64(len(p))
+	t.report(BytesTransferred, nil)
+	return len(p), nil
+}
+
+func (t *progressTracker) startPart(part int) {
+	t.part = part
+	t.report(PartStarted, nil)
+}
+
+func (t *progressTracker) report(typ ProgressEventType, err error) {
+	ev := ProgressEvent{
+		Type:    typ,
+		File:    t.file,
+		Part:    t.part,
+		Parts:   t.file.Parts,
+		Current: t.current,
+		Total:   t.total,
+		Elapsed: time.Since(t.start),
+		Err:     err,
+	}
+	if secs := ev.Elapsed.Seconds(); secs > 0 {
+		ev.Rate = float64(t.current) / secs
+	}
+	if ev.Rate > 0 && t.total > t.current {
+		ev.ETA = time.Duration(float64(t.total-t.current) / ev.Rate * float64(time.Second))
+	}
+	t.reporter.Report(ev)
+}
+
+// MultiBar renders a progress bar per file to a terminal, redrawing them
+// in place so several concurrent transfers can be followed at once. When
+// Plain is set, as it is for writers that aren't a terminal, each update
+// is printed as a line of its own instead, with no escape codes, so logs
+// and pipes stay readable.
+type MultiBar struct {
+	Plain bool
+
+	w        io.Writer
+	interval time.Duration
+
+	mu       sync.Mutex
+	order    []string
+	bars     map[string]ProgressEvent
+	drawn    int
+	lastDraw time.Time
+}
+
+func NewMultiBar(w io.Writer) *MultiBar {
+	return &MultiBar{
+		Plain:    !isTerminal(w),
+		w:        w,
+		interval: 100 * time.Millisecond,
+		bars:     make(map[string]ProgressEvent),
+	}
+}
+
+func (m *MultiBar) Report(ev ProgressEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := ev.File.FileID + "/" + ev.File.FileName
+	if _, ok := m.bars[key]; !ok {
+		m.order = append(m.order, key)
+	}
+	m.bars[key] = ev
+
+	if m.Plain {
+		m.printLine(ev)
+		return
+	}
+	if ev.Type == BytesTransferred && time.Since(m.lastDraw) < m.interval {
+		return
+	}
+	m.draw()
+}
+
+// Flush redraws the bars with their latest state. Call it once all
+// transfers are done.
+func (m *MultiBar) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Plain {
+		return
+	}
+	m.draw()
+}
+
+// plainInterval is how often a plain MultiBar prints bytes transferred, so
+// a log gets a line every few seconds rather than one per write.
+const plainInterval = 5 * time.Second
+
+func (m *MultiBar) printLine(ev ProgressEvent) {
+	switch ev.Type {
+	case PartStarted, PartFinished:
+		return
+	case BytesTransferred:
+		if time.Since(m.lastDraw) < plainInterval {
+			return
+		}
+	}
+	m.lastDraw = time.Now()
+	io.WriteString(m.w, formatBar(ev)+"\n")
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (m *MultiBar) draw() {
+	var b strings.Builder
+	if m.drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", m.drawn)
+	}
+	for _, key := range m.order {
+		b.WriteString("\x1b[2K")
+		b.WriteString(formatBar(m.bars[key]))
+		b.WriteString("\n")
+	}
+	m.drawn = len(m.order)
+	m.lastDraw = time.Now()
+	io.WriteString(m.w, b.String())
+}
+
+const barWidth = 25
+
+func formatBar(ev ProgressEvent) string {
+	name := ev.File.FileName
+	if len(name) > 30 {
+		name = name[:27] + "..."
+	}
+
+	pct := 0.0
+	if ev.Total > 0 {
+		pct = float64(ev.Current) / float64(ev.Total)
+	}
+	if pct > 1 || (ev.Total == 0 && ev.Type == FileFinished) {
+		pct = 1
+	}
+	filled := int(pct * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+
+	status := fmt.Sprintf("%s/s", humanize.Bytes(uint64(ev.Rate)))
+	switch {
+	case ev.Type == FileFinished && ev.Err != nil:
+		status = "failed: " + ev.Err.Error()
+	case ev.Type == FileFinished:
+		status = fmt.Sprintf("%s/s done in %s", humanize.Bytes(uint64(ev.Rate)), ev.Elapsed.Round(time.Second))
+	case ev.ETA > 0:
+		status += fmt.Sprintf(" ETA %s", ev.ETA.Round(time.Second))
+	}
+
+	// Streamed uploads don't know their size until they finish.
+	total := humanize.Bytes(ev.Total)
+	if ev.Total == 0 && ev.Type != FileFinished {
+		total = "?"
+	}
+
+	return fmt.Sprintf("%-30s [%s] %3.0f%% %s/%s %s",
+		name, bar, pct*100, humanize.Bytes(ev.Current), total, status)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProgressTracker(t *testing.T) {
+	var events []ProgressEvent
+	f := File{FileID: "1", FileName: "logs.tgz", FileSize: "10", Parts: 2}
+
+	tracker := newProgressTracker(ProgressFunc(func(ev ProgressEvent) {
+		events = append(events, ev)
+	}), f, 10)
+	tracker.report(FileStarted, nil)
+	tracker.startPart(1)
+	tracker.Write(make([]byte, 4))
+	tracker.report(PartFinished, nil)
+	tracker.startPart(2)
+	tracker.Write(make([]byte, 6))
+	tracker.report(PartFinished, nil)
+	tracker.report(FileFinished, nil)
+
+	expected := []struct {
+		typ     ProgressEventType
+		part    int
+		current uint64
+	}{
+		{FileStarted, 0, 0},
+		{PartStarted, 1, 0},
+		{BytesTransferred, 1, 4},
+		{PartFinished, 1, 4},
+		{PartStarted, 2, 4},
+		{BytesTransferred, 2, 10},
+		{PartFinished, 2, 10},
+		{FileFinished, 2, 10},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("Got %d events, want %d.", len(events), len(expected))
+	}
+	for i, e := range expected {
+		ev := events[i]
+		if ev.Type != e.typ || ev.Part != e.part || ev.Current != e.current || ev.Total != 10 || ev.Parts != 2 {
+			t.Errorf("Event %d was incorrect, got: (%s, %d, %d/%d), want: (%s, %d, %d/10).", i, ev.Type, ev.Part, ev.Current, ev.Total, e.typ, e.part, e.current)
+		}
+	}
+}
+
+func TestBytesProgress(t *testing.T) {
+	calls := 0
+	r := BytesProgress(func(current uint64, total uint64) {
+		calls++
+		if current != 4 || total != 10 {
+			t.Errorf("BytesProgress was incorrect, got: (%d, %d), want: (4, 10).", current, total)
+		}
+	})
+
+	r.Report(ProgressEvent{Type: FileStarted, Total: 10})
+	r.Report(ProgressEvent{Type: BytesTransferred, Current: 4, Total: 10})
+	r.Report(ProgressEvent{Type: FileFinished, Current: 4, Total: 10})
+
+	if calls != 1 {
+		t.Errorf("BytesProgress was called %d times, want 1.", calls)
+	}
+}
+
+func TestMultiBar(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMultiBar(&buf)
+	m.Plain = false
+
+	a := File{FileID: "1", FileName: "a.txt"}
+	b := File{FileID: "2", FileName: "b.txt"}
+	m.Report(ProgressEvent{Type: FileStarted, File: a, Total: 100})
+	m.Report(ProgressEvent{Type: FileStarted, File: b, Total: 100})
+	m.Report(ProgressEvent{Type: FileFinished, File: a, Current: 100, Total: 100})
+	m.Report(ProgressEvent{Type: FileFinished, File: b, Current: 50, Total: 100, Err: errors.New("Got HTTP status code: 500")})
+	m.Flush()
+
+	out := buf.String()
+	last := out[strings.LastIndex(out, "\x1b[2A"):]
+	lines := strings.Split(strings.TrimSuffix(last, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("MultiBar drew %d lines, want 2: %q", len(lines), last)
+	}
+	if !strings.Contains(lines[0], "a.txt") || !strings.Contains(lines[0], "100%") || !strings.Contains(lines[0], "done") {
+		t.Errorf("MultiBar line was incorrect, got: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "b.txt") || !strings.Contains(lines[1], " 50%") || !strings.Contains(lines[1], "failed: Got HTTP status code: 500") {
+		t.Errorf("MultiBar line was incorrect, got: %q", lines[1])
+	}
+}
+
+func TestMultiBarPlain(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMultiBar(&buf)
+	if !m.Plain {
+		t.Fatalf("MultiBar should be plain when not writing to a terminal.")
+	}
+
+	a := File{FileID: "1", FileName: "a.txt", Parts: 1}
+	m.Report(ProgressEvent{Type: FileStarted, File: a, Total: 100})
+	m.Report(ProgressEvent{Type: PartStarted, File: a, Part: 1, Total: 100})
+	m.Report(ProgressEvent{Type: BytesTransferred, File: a, Part: 1, Current: 50, Total: 100})
+	m.Report(ProgressEvent{Type: BytesTransferred, File: a, Part: 1, Current: 60, Total: 100})
+	m.Report(ProgressEvent{Type: PartFinished, File: a, Part: 1, Current: 100, Total: 100})
+	m.Report(ProgressEvent{Type: FileFinished, File: a, Current: 100, Total: 100})
+	m.Flush()
+
+	out := buf.String()
+	if strings.Contains(out, "\x1b") {
+		t.Errorf("MultiBar wrote escape codes when plain: %q", out)
+	}
+	// The bytes updates come too soon after the start to be printed.
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	expected := []string{"  0%", "100%"}
+	if len(lines) != len(expected) {
+		t.Fatalf("MultiBar printed %d lines, want %d: %q", len(lines), len(expected), out)
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], "a.txt") || !strings.Contains(lines[i], e) {
+			t.Errorf("MultiBar line was incorrect, got: %q, want it to contain: %q", lines[i], e)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"golang.org/x/time/rate"
+)
+
+// maxBurst bounds how many bytes a limited transfer reads at once, which
+// keeps throughput smooth rather than arriving in large bursts.
+const maxBurst = 256 * 1024
+
+// SetBandwidthLimit caps the combined throughput of all transfers made
+// through the API at bytesPerSecond. Zero removes the limit.
+func (a *API) SetBandwidthLimit(bytesPerSecond int64) {
+	a.bandwidth = newByteLimiter(bytesPerSecond)
+}
+
+// SetTransferBandwidthLimit caps the throughput of each file transfer at
+// bytesPerSecond. Zero removes the limit.
+func (a *API) SetTransferBandwidthLimit(bytesPerSecond int64) {
+	a.transferBandwidth = bytesPerSecond
+}
+
+func (a *API) newTransferLimiter() *rate.Limiter {
+	return newByteLimiter(a.transferBandwidth)
+}
+
+func newByteLimiter(bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	burst := bytesPerSecond
+	if burst > maxBurst {
+		burst = maxBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// limitReader applies the API wide limit and the given transfer limit,
+// either of which may be nil, to reads from r.
+func (a *API) limitReader(r io.Reader, transfer *rate.Limiter) io.Reader {
+	limiters := []*rate.Limiter{}
+	for _, l := range []*rate.Limiter{a.bandwidth, transfer} {
+		if l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+	if len(limiters) == 0 {
+		return r
+	}
+	return &limitedReader{r, limiters}
+}
+
+type limitedReader struct {
+	r        io.Reader
+	limiters []*rate.Limiter
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	for _, lim := range l.limiters {
+		if len(p) > lim.Burst() {
+			p = p[:lim.Burst()]
+		}
+	}
+
+	n, err := l.r.Read(p)
+	for _, lim := range l.limiters {
+		if werr := lim.WaitN(context.Background(), n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// ParseByteRate parses rates such as "5MB/s", "500KiB/s" or "1048576".
+func ParseByteRate(s string) (int64, error) {
+	b, err := humanize.ParseBytes(strings.TrimSuffix(strings.TrimSpace(s), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid rate %q: %s", s, err)
+	}
+	return int64(b), nil
+}
+
+var (
+	// MaxRetries is how many times a throttled request is retried.
+	MaxRetries = 5
+	// ThrottledRequestRate is the request rate assumed when the server
+	// throttles an API with no request limit set.
+	ThrottledRequestRate = rate.Limit(10)
+	// MinRequestRate is the lowest rate throttling will slow down to.
+	MinRequestRate = rate.Limit(0.2)
+)
+
+// SetRequestRateLimit limits API requests to perSecond, allowing bursts of
+// burst requests. The rate is lowered while the server responds with 429
+// Too Many Requests and recovers towards perSecond afterwards.
+func (a *API) SetRequestRateLimit(perSecond float64, burst int) {
+	a.throttleMu.Lock()
+	defer a.throttleMu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	a.requestLimit = rate.Limit(perSecond)
+	if perSecond <= 0 {
+		a.requestLimit = rate.Inf
+	}
+	a.requests = rate.NewLimiter(a.requestLimit, burst)
+}
+
+// SetMaxConcurrentRequests caps the number of requests in flight at once,
+// including downloads still streaming their response. Zero removes the
+// cap. It must be called before the API is used.
+func (a *API) SetMaxConcurrentRequests(n int) {
+	if n <= 0 {
+		a.requestSlots = nil
+		return
+	}
+	a.requestSlots = make(chan struct{}, n)
+}
+
+// acquire waits until a request may be sent. The returned func must be
+// called once the request is finished with.
+func (a *API) acquire() (func(), error) {
+	err := a.requests.Wait(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	slots := a.requestSlots
+	if slots == nil {
+		return func() {}, nil
+	}
+	slots <- struct{}{}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+// throttled halves the request rate and returns how long to wait before
+// retrying, preferring the server's Retry-After if it sent one.
+func (a *API) throttled(retryAfter string, attempt int) time.Duration {
+	a.throttleMu.Lock()
+	defer a.throttleMu.Unlock()
+
+	a.successes = 0
+	limit := a.requests.Limit()
+	if limit == rate.Inf {
+		limit = ThrottledRequestRate
+	}
+	limit /= 2
+	if limit < MinRequestRate {
+		limit = MinRequestRate
+	}
+	a.requests.SetLimit(limit)
+
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Duration(1<<uint(attempt-1)) * time.Second
+}
+
+// unthrottled raises the request rate back towards the configured limit
+// after a run of successful requests.
+func (a *API) unthrottled() {
+	a.throttleMu.Lock()
+	defer a.throttleMu.Unlock()
+
+	limit := a.requests.Limit()
+	if limit >= a.requestLimit {
+		return
+	}
+
+	a.successes++
+	if a.successes < 10 {
+		return
+	}
+	a.successes = 0
+
+	limit *= 1.25
+	if limit >= a.requestLimit || (a.requestLimit == rate.Inf && limit > 4*ThrottledRequestRate) {
+		limit = a.requestLimit
+	}
+	a.requests.SetLimit(limit)
+}
+
+// releasingBody gives up the request's concurrency slot when the response
+// body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseByteRate(t *testing.T) {
+	tables := []struct {
+		rate     string
+		expected int64
+		err      bool
+	}{
+		{"5MB/s", 5000000, false},
+		{"500KiB/s", 512000, false},
+		{"1048576", 1048576, false},
+		{" 2 GB/s ", 2000000000, false},
+		{"fast", 0, true},
+	}
+
+	for _, table := range tables {
+		result, err := ParseByteRate(table.rate)
+		if result != table.expected || (err != nil) != table.err {
+			t.Errorf("ParseByteRate of %s was incorrect, got: (%d, %v), want: (%d, %t).", table.rate, result, err, table.expected, table.err)
+		}
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	a := NewAPI("host", "key", "secret")
+	a.SetBandwidthLimit(64 * 1024)
+
+	// The first 64KiB is covered by the burst, the next 32KiB take half a
+	// second.
+	data := bytes.Repeat([]byte("x"), 96*1024)
+	start := time.Now()
+	b, err := ioutil.ReadAll(a.limitReader(bytes.NewReader(data), nil))
+	elapsed := time.Since(start)
+
+	if err != nil || !bytes.Equal(b, data) {
+		t.Fatalf("limitReader changed the data read, got %d bytes, error %v", len(b), err)
+	}
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("limitReader took %s to read 96KiB at 64KiB/s", elapsed)
+	}
+}
+
+func TestThrottledRetry(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"response":"SUCCESS"}`))
+	}))
+	defer srv.Close()
+
+	a := NewAPI(srv.URL, "key", "secret")
+	a.SetMaxConcurrentRequests(1)
+
+	err := a.requestJSON("/user/", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("requestJSON returned error: %s", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the throttled request to be retried once, got %d calls", calls)
+	}
+	if a.requests.Limit() != ThrottledRequestRate/2 {
+		t.Errorf("Request rate after throttling was incorrect, got: %v, want: %v.", a.requests.Limit(), ThrottledRequestRate/2)
+	}
+
+	// The concurrency slot must have been released for this not to block.
+	err = a.requestJSON("/user/", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("requestJSON returned error: %s", err)
+	}
+}
+
+func TestUnthrottled(t *testing.T) {
+	a := NewAPI("host", "key", "secret")
+	a.SetRequestRateLimit(8, 1)
+
+	a.throttled("", 1)
+	if a.requests.Limit() != 4 {
+		t.Fatalf("Request rate after throttling was incorrect, got: %v, want: %v.", a.requests.Limit(), 4)
+	}
+
+	for i := 0; i < 30; i++ {
+		a.unthrottled()
+	}
+	if a.requests.Limit() != 7.8125 {
+		t.Errorf("Request rate after 30 successes was incorrect, got: %v, want: %v.", a.requests.Limit(), 7.8125)
+	}
+
+	for i := 0; i < 10; i++ {
+		a.unthrottled()
+	}
+	if a.requests.Limit() != 8 {
+		t.Errorf("Request rate should recover to the configured limit, got: %v, want: %v.", a.requests.Limit(), 8)
+	}
+}
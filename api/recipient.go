@@ -0,0 +1,63 @@
+package api
+
+import "strings"
+
+// FindRecipient looks up a recipient of the package by email address.
+func (p *Package) FindRecipient(email string) (Recipient, bool) {
+	for _, r := range p.Recipients {
+		if strings.EqualFold(r.Email, email) {
+			return r, true
+		}
+	}
+	return Recipient{}, false
+}
+
+func (a *API) AddRecipient(packageID string, email string) (Recipient, error) {
+	var r Recipient
+	path := "/package/" + packageID + "/recipient/"
+
+	postParams := make(map[string]string, 1)
+	postParams["email"] = email
+
+	err := a.requestJSON(path, "PUT", postParams, &r)
+	if err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func (a *API) RemoveRecipient(packageID string, recipientID string) error {
+	path := "/package/" + packageID + "/recipient/" + recipientID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
+
+// UpdateRecipientPhone sets the number recipients are sent an SMS
+// verification code on. countryCode is the ISO code, e.g. "US".
+func (a *API) UpdateRecipientPhone(packageID string, recipientID string, phoneNumber string, countryCode string) error {
+	path := "/package/" + packageID + "/recipient/" + recipientID + "/"
+
+	postParams := make(map[string]string, 2)
+	postParams["phoneNumber"] = phoneNumber
+	postParams["countrycode"] = countryCode
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+func (a *API) UpdateRecipientRole(packageID string, recipientID string, roleName string) error {
+	path := "/package/" + packageID + "/recipient/" + recipientID + "/"
+
+	postParams := make(map[string]string, 1)
+	postParams["roleName"] = roleName
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+func (a *API) AddContactGroupToPackage(packageID string, groupID string) error {
+	path := "/package/" + packageID + "/group/" + groupID + "/"
+	return a.requestJSON(path, "PUT", nil, nil)
+}
+
+func (a *API) RemoveContactGroupFromPackage(packageID string, groupID string) error {
+	path := "/package/" + packageID + "/group/" + groupID + "/"
+	return a.requestJSON(path, "DELETE", nil, nil)
+}
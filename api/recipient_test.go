@@ -0,0 +1,29 @@
+package api
+
+import "testing"
+
+func TestFindRecipient(t *testing.T) {
+	p := Package{
+		Recipients: []Recipient{
+			{RecipientID: "r1", Email: "user1@test.com"},
+			{RecipientID: "r2", Email: "User2@Test.com"},
+		},
+	}
+
+	tables := []struct {
+		email    string
+		expected string
+		found    bool
+	}{
+		{"user1@test.com", "r1", true},
+		{"user2@test.com", "r2", true},
+		{"user3@test.com", "", false},
+	}
+
+	for _, table := range tables {
+		r, found := p.FindRecipient(table.email)
+		if r.RecipientID != table.expected || found != table.found {
+			t.Errorf("FindRecipient(%s) was incorrect, got: (%s, %t), want: (%s, %t).", table.email, r.RecipientID, found, table.expected, table.found)
+		}
+	}
+}
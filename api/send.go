@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/time/rate"
+)
+
+// NewKeyCode generates the client side half of a package's encryption
+// key. It never leaves the client except as part of the secure link.
+func NewKeyCode() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreatePackage starts a new package and returns it along with metadata
+// holding a freshly generated keyCode.
+func (a *API) CreatePackage() (Package, PackageMetadata, error) {
+	var p Package
+	var pm PackageMetadata
+
+	keyCode, err := NewKeyCode()
+	if err != nil {
+		return p, pm, err
+	}
+
+	postParams := make(map[string]bool, 1)
+	postParams["vdr"] = false
+
+	err = a.requestJSON("/package/", "PUT", postParams, &p)
+	if err != nil {
+		return p, pm, err
+	}
+
+	pm.PackageCode = p.PackageCode
+	pm.KeyCode = keyCode
+	return p, pm, nil
+}
+
+// FinalizePackage makes a package available to its recipients and returns
+// the secure link, including the keyCode.
+func (a *API) FinalizePackage(pm PackageMetadata, p Package) (string, error) {
+	return a.finalize(pm, p, map[string]string{})
+}
+
+func (a *API) finalize(pm PackageMetadata, p Package, postParams map[string]string) (string, error) {
+	var r apiResponse
+	path := "/package/" + p.PackageID + "/finalize/"
+
+	postParams["checksum"] = createChecksum(pm.KeyCode, p.PackageCode)
+
+	err := a.requestJSON(path, "POST", postParams, &r)
+	if err != nil {
+		return "", err
+	}
+
+	return r.Message + "#keyCode=" + pm.KeyCode, nil
+}
+
+// AddFile registers a file of size bytes with a package so its parts can
+// be uploaded. A size of -1 registers a file whose length isn't known yet;
+// its size and part count are sent once the upload completes.
+func (a *API) AddFile(p Package, name string, size int64) (File, error) {
+	var f File
+	path := "/package/" + p.PackageID + "/file/"
+
+	postParams := make(map[string]interface{}, 4)
+	postParams["filename"] = name
+	postParams["uploadType"] = DownloadAPI
+
+	parts := 0
+	if size >= 0 {
+		parts = int((size + PartSize - 1) / PartSize)
+		if parts == 0 {
+			parts = 1
+		}
+		postParams["parts"] = parts
+		postParams["filesize"] = size
+	}
+
+	err := a.requestJSON(path, "PUT", postParams, &f)
+	if err != nil {
+		return f, err
+	}
+
+	f.FileName = name
+	if size >= 0 {
+		f.FileSize = strconv.FormatInt(size, 10)
+	}
+	f.Parts = parts
+	return f, nil
+}
+
+// UploadFile encrypts the file at fp part by part and uploads it to the
+// package.
+func (a *API) UploadFile(pm PackageMetadata, p Package, fp string, progress ProgressReporter) (File, error) {
+	fh, err := os.Open(fp)
+	if err != nil {
+		return File{}, err
+	}
+	defer fh.Close()
+
+	fi, err := fh.Stat()
+	if err != nil {
+		return File{}, err
+	}
+
+	size := fi.Size()
+	if !fi.Mode().IsRegular() {
+		size = -1
+	}
+	return a.UploadReader(pm, p, filepath.Base(fp), fh, size, progress)
+}
+
+// UploadReader uploads everything read from r as a file called name. size
+// is the number of bytes r will return, or -1 if it isn't known in advance,
+// e.g. when reading from a pipe. Parts are encrypted and uploaded as they
+// are read, so only one part is held in memory at a time.
+func (a *API) UploadReader(pm PackageMetadata, p Package, name string, r io.Reader, size int64, progress ProgressReporter) (File, error) {
+	f, err := a.AddFile(p, name, size)
+	if err != nil {
+		return f, err
+	}
+
+	var total uint64
+	if size > 0 {
+		total = uint64(size)
+	}
+	tracker := newProgressTracker(progress, f, total)
+	tracker.report(FileStarted, nil)
+
+	parts, n, err := a.uploadParts(pm, p, f, r, tracker)
+	if err == nil {
+		f.Parts = parts
+		f.FileSize = strconv.FormatInt(n, 10)
+		err = a.markFileComplete(p, f)
+	}
+	tracker.report(FileFinished, err)
+	return f, err
+}
+
+// uploadParts uploads r until it is exhausted, or until f.Parts parts have
+// been uploaded if the size was known up front. It returns the number of
+// parts and bytes uploaded.
+func (a *API) uploadParts(pm PackageMetadata, p Package, f File, r io.Reader, tracker *progressTracker) (int, int64, error) {
+	var size int64
+	transfer := a.newTransferLimiter()
+	buf := make([]byte, PartSize)
+	for i := 1; ; i++ {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF && i > 1 {
+			// The previous part ended exactly on a part boundary.
+			return i - 1, size, nil
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return i - 1, size, err
+		}
+
+		tracker.startPart(i)
+		err = a.uploadPart(pm, p, f, i, buf[:n], transfer)
+		if err != nil {
+			return i - 1, size, err
+		}
+		size += int64(n)
+		tracker.Write(buf[:n])
+		tracker.report(PartFinished, nil)
+
+		if n < len(buf) || i == f.Parts {
+			return i, size, nil
+		}
+	}
+}
+
+func (a *API) uploadPart(pm PackageMetadata, p Package, f File, part int, data []byte, transfer *rate.Limiter) error {
+	var r struct {
+		UploadURLs []struct {
+			Part int    `json:"part"`
+			URL  string `json:"url"`
+		} `json:"uploadUrls"`
+	}
+	path := "/package/" + p.PackageID + "/file/" + f.FileID + "/upload-urls/"
+
+	postParams := make(map[string]int, 1)
+	postParams["part"] = part
+
+	err := a.requestJSON(path, "POST", postParams, &r)
+	if err != nil {
+		return err
+	}
+	if len(r.UploadURLs) == 0 {
+		return fmt.Errorf("No upload URL for part %d", part)
+	}
+
+	var buf bytes.Buffer
+	err = encryptPart(&buf, data, partPassword(p.ServerSecret, pm.KeyCode), f.FileName)
+	if err != nil {
+		return err
+	}
+
+	// Upload URLs are pre-signed, so the request isn't signed with the
+	// API credentials.
+	req, err := http.NewRequest("PUT", r.UploadURLs[0].URL, a.limitReader(&buf, transfer))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(buf.Len())
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Got HTTP status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *API) markFileComplete(p Package, f File) error {
+	path := "/package/" + p.PackageID + "/file/" + f.FileID + "/upload-complete/"
+
+	postParams := make(map[string]interface{}, 3)
+	postParams["complete"] = true
+	postParams["parts"] = f.Parts
+	postParams["filesize"] = f.FileSizeInt()
+
+	return a.requestJSON(path, "POST", postParams, nil)
+}
+
+func encryptPart(w io.Writer, data []byte, password []byte, name string) error {
+	hints := &openpgp.FileHints{
+		IsBinary: true,
+		FileName: name,
+	}
+
+	pw, err := openpgp.SymmetricallyEncrypt(w, password, hints, encryptionConfig)
+	if err != nil {
+		return err
+	}
+	_, err = pw.Write(data)
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
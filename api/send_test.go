@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewKeyCode(t *testing.T) {
+	a, err := NewKeyCode()
+	if err != nil {
+		t.Fatalf("NewKeyCode returned error: %s", err)
+	}
+	b, _ := NewKeyCode()
+
+	if len(a) != 43 {
+		t.Errorf("NewKeyCode length was incorrect, got: %d, want: %d.", len(a), 43)
+	}
+	if a == b {
+		t.Errorf("NewKeyCode returned the same keyCode twice: %s", a)
+	}
+}
+
+func TestEncryptPart(t *testing.T) {
+	tables := []struct {
+		data     []byte
+		password string
+	}{
+		{[]byte("part one of the logs"), "serverSecretkeyCode"},
+		{[]byte{}, "serverSecretkeyCode"},
+		{bytes.Repeat([]byte{0, 1, 2, 255}, 4096), "aXaQiWhw9p29CAoDoLRxpWbzotX2Qe0D-0agiN_RYXU"},
+	}
+
+	for _, table := range tables {
+		var buf bytes.Buffer
+		err := encryptPart(&buf, table.data, []byte(table.password), "logs.tgz")
+		if err != nil {
+			t.Fatalf("encryptPart returned error: %s", err)
+		}
+
+		md, err := decryptMessage(&buf, []byte(table.password))
+		if err != nil {
+			t.Fatalf("decryptMessage returned error: %s", err)
+		}
+		result, err := ioutil.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			t.Fatalf("reading decrypted part returned error: %s", err)
+		}
+		if !bytes.Equal(result, table.data) {
+			t.Errorf("encryptPart round trip was incorrect, got %d bytes, want %d bytes.", len(result), len(table.data))
+		}
+	}
+}
+
+// uploadServer fakes the endpoints used to upload a file, recording the
+// size of each part and the parameters the upload was completed with.
+type uploadServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	added    map[string]interface{}
+	parts    []int
+	complete map[string]interface{}
+}
+
+func newUploadServer() *uploadServer {
+	s := &uploadServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		b, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/file/"):
+			json.Unmarshal(b, &s.added)
+			w.Write([]byte(`{"response":"SUCCESS","fileId":"F1"}`))
+		case strings.HasSuffix(r.URL.Path, "/upload-urls/"):
+			w.Write([]byte(`{"response":"SUCCESS","uploadUrls":[{"part":1,"url":"` + s.URL + `/s3/"}]}`))
+		case r.URL.Path == "/s3/":
+			s.parts = append(s.parts, len(b))
+		case strings.HasSuffix(r.URL.Path, "/upload-complete/"):
+			json.Unmarshal(b, &s.complete)
+			w.Write([]byte(`{"response":"SUCCESS"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return s
+}
+
+func TestUploadReader(t *testing.T) {
+	defer func(size int64) { PartSize = size }(PartSize)
+	PartSize = 1024
+
+	tables := []struct {
+		size          int
+		known         bool
+		expectedParts int
+	}{
+		{2500, false, 3},
+		{2048, false, 2},
+		{0, false, 1},
+		{2500, true, 3},
+		{2048, true, 2},
+	}
+
+	for _, table := range tables {
+		srv := newUploadServer()
+		a := NewAPI(srv.URL, "key", "secret")
+
+		data := bytes.Repeat([]byte("x"), table.size)
+		size := int64(-1)
+		// Hide the length of the data, as a pipe would.
+		var r io.Reader = struct{ io.Reader }{bytes.NewReader(data)}
+		if table.known {
+			size = int64(table.size)
+		}
+
+		f, err := a.UploadReader(PackageMetadata{KeyCode: "keycode"}, Package{PackageID: "P1", ServerSecret: "secret"}, "stdin", r, size, nil)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("UploadReader(%d, %t) returned error: %s", table.size, table.known, err)
+		}
+
+		if f.Parts != table.expectedParts || f.FileSizeInt() != uint64(table.size) {
+			t.Errorf("UploadReader(%d, %t) file was incorrect, got: (%d parts, %d bytes), want: (%d parts, %d bytes).", table.size, table.known, f.Parts, f.FileSizeInt(), table.expectedParts, table.size)
+		}
+		if len(srv.parts) != table.expectedParts {
+			t.Errorf("UploadReader(%d, %t) uploaded %d parts, want %d.", table.size, table.known, len(srv.parts), table.expectedParts)
+		}
+		if _, ok := srv.added["filesize"]; ok != table.known {
+			t.Errorf("UploadReader(%d, %t) registered the file with %v.", table.size, table.known, srv.added)
+		}
+		if srv.complete["parts"] != float64(table.expectedParts) || srv.complete["filesize"] != float64(table.size) {
+			t.Errorf("UploadReader(%d, %t) completed the upload with %v.", table.size, table.known, srv.complete)
+		}
+	}
+}
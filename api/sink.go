@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sink is somewhere downloaded files can be written. DownloadTo streams
+// the decrypted parts of a file into the SinkWriter returned by Create.
+type Sink interface {
+	// Create starts writing the file called name. size is the size of the
+	// decrypted file and tags describe the package it came from.
+	Create(name string, size int64, tags map[string]string) (SinkWriter, error)
+}
+
+// SinkWriter receives a single file. Close commits it, Abort discards
+// whatever has been written.
+type SinkWriter interface {
+	io.WriteCloser
+	Abort(err error) error
+}
+
+// DirSink writes files into a directory on the local filesystem. Tags are
+// ignored.
+type DirSink string
+
+// CheckSinkName refuses names that aren't a single path element. File
+// names are chosen by the sender, and sinks must not let them write
+// outside their destination.
+func CheckSinkName(name string) error {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%s: Refusing to write a file name containing a path", name)
+	}
+	return nil
+}
+
+func (d DirSink) Create(name string, size int64, tags map[string]string) (SinkWriter, error) {
+	if err := CheckSinkName(name); err != nil {
+		return nil, err
+	}
+	fp := filepath.Join(string(d), name)
+	fh, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("File exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fileSinkWriter{fh}, nil
+}
+
+type fileSinkWriter struct {
+	*os.File
+}
+
+func (w *fileSinkWriter) Abort(err error) error {
+	w.File.Close()
+	return os.Remove(w.File.Name())
+}
+
+// PackageTags describes the package f came from, for sinks that can store
+// metadata alongside a file. The keyCode is never included.
+func PackageTags(pm PackageMetadata, p Package, f File) map[string]string {
+	return map[string]string{
+		"sendsafely-package-id":        p.PackageID,
+		"sendsafely-package-code":      p.PackageCode,
+		"sendsafely-thread":            pm.Thread,
+		"sendsafely-package-sender":    p.PackageSender,
+		"sendsafely-package-timestamp": p.PackageTimestamp,
+		"sendsafely-file-id":           f.FileID,
+		"sendsafely-file-name":         f.FileName,
+	}
+}
+
+// DownloadTo downloads and decrypts f, streaming it into a new file
+// called name in sink. Nothing is staged on disk.
+func (a *API) DownloadTo(pm PackageMetadata, p Package, f File, sink Sink, name string, progress ProgressReporter) (err error) {
+	tracker := newProgressTracker(progress, f, f.FileSizeInt())
+	tracker.report(FileStarted, nil)
+	defer func() {
+		tracker.report(FileFinished, err)
+	}()
+
+	w, err := sink.Create(name, int64(f.FileSizeInt()), PackageTags(pm, p, f))
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	err = a.copyParts(io.MultiWriter(w, h), pm, p, f, tracker)
+	if err != nil {
+		w.Abort(err)
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	// Hooks need the file on local disk.
+	if fw, ok := w.(*fileSinkWriter); ok {
+		return a.runFileHooks(DownloadedFile{
+			Package: p,
+			File:    f,
+			Path:    fw.Name(),
+			SHA256:  hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+	return nil
+}
+
+func (a *API) copyParts(w io.Writer, pm PackageMetadata, p Package, f File, tracker *progressTracker) error {
+	transfer := a.newTransferLimiter()
+	for i := 1; i <= f.Parts; i++ {
+		tracker.startPart(i)
+		r, err := a.openPart(pm, p, f, i, transfer)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, io.TeeReader(r, tracker))
+		r.Close()
+		if err != nil {
+			return err
+		}
+		tracker.report(PartFinished, nil)
+	}
+	return nil
+}
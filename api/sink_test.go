@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := DirSink(dir)
+
+	w, err := sink.Create("kept.txt", 5, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %s", err)
+	}
+	w.Write([]byte("hello"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %s", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "kept.txt"))
+	if err != nil || string(b) != "hello" {
+		t.Errorf("DirSink wrote %q, %v, want: \"hello\".", b, err)
+	}
+
+	if _, err := sink.Create("kept.txt", 5, nil); err == nil || err.Error() != "File exists" {
+		t.Errorf("Create of an existing file was incorrect, got: %v, want: File exists.", err)
+	}
+
+	w, err = sink.Create("aborted.txt", 5, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %s", err)
+	}
+	w.Write([]byte("hel"))
+	w.Abort(errors.New("Download failed"))
+	if _, err := os.Stat(filepath.Join(dir, "aborted.txt")); !os.IsNotExist(err) {
+		t.Errorf("Aborted file should have been removed, got: %v", err)
+	}
+}
+
+func TestCheckSinkName(t *testing.T) {
+	tables := []struct {
+		name  string
+		valid bool
+	}{
+		{"logs.tgz", true},
+		{".bashrc", true},
+		{"..logs", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../../.bashrc", false},
+		{"/etc/passwd", false},
+		{"logs/../../x", false},
+		{`..\evil.bat`, false},
+	}
+
+	for _, table := range tables {
+		err := CheckSinkName(table.name)
+		if (err == nil) != table.valid {
+			t.Errorf("CheckSinkName(%q) was incorrect, got: %v, want valid: %t.", table.name, err, table.valid)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "gosafely")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := DirSink(filepath.Join(dir, "dest")).Create("../escaped.txt", 5, nil); err == nil {
+		t.Errorf("DirSink created a file outside its directory.")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("DirSink wrote outside its directory, got: %v", err)
+	}
+}
+
+func TestPackageTags(t *testing.T) {
+	pm := PackageMetadata{Thread: "ABCD-EFGH", PackageCode: "11aa22bb33cc", KeyCode: "dd44ee55ff66"}
+	p := Package{PackageID: "ABCD-EFGH", PackageCode: "11aa22bb33cc", ServerSecret: "secret"}
+	f := File{FileID: "1", FileName: "logs.tgz"}
+
+	tags := PackageTags(pm, p, f)
+	if tags["sendsafely-file-id"] != "1" || tags["sendsafely-package-id"] != "ABCD-EFGH" {
+		t.Errorf("PackageTags was incorrect, got: %v", tags)
+	}
+	for k, v := range tags {
+		if v == pm.KeyCode || v == p.ServerSecret {
+			t.Errorf("PackageTags should not include secrets, got %s=%s", k, v)
+		}
+	}
+}
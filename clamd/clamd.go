@@ -0,0 +1,211 @@
+// Package clamd scans downloaded files with a ClamAV daemon, or anything
+// that speaks its INSTREAM protocol.
+package clamd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+// Action is what happens to an infected file.
+type Action string
+
+var (
+	// Flag leaves the file where it is and only reports it.
+	Flag Action = "flag"
+	// Quarantine moves the file into the quarantine directory.
+	Quarantine Action = "quarantine"
+	// Delete removes the file.
+	Delete Action = "delete"
+)
+
+// ChunkSize is the most sent to clamd in a single INSTREAM chunk. It must
+// be below clamd's StreamMaxLength.
+var ChunkSize = 64 * 1024
+
+// Scanner implements gosafely.FileHook, scanning each downloaded file and
+// acting on any found to be infected.
+type Scanner struct {
+	Network       string
+	Address       string
+	Action        Action
+	QuarantineDir string
+	Timeout       time.Duration
+}
+
+// New returns a Scanner for clamd at addr, either a unix socket path or
+// host:port. Infected files are flagged unless Action is changed.
+func New(addr string) *Scanner {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") || strings.HasPrefix(addr, "unix:") {
+		network = "unix"
+		addr = strings.TrimPrefix(addr, "unix:")
+	}
+	return &Scanner{
+		Network: network,
+		Address: addr,
+		Action:  Flag,
+		Timeout: 5 * time.Minute,
+	}
+}
+
+// InfectedError is returned for a file clamd found a signature in, after
+// the Scanner's Action has been taken.
+type InfectedError struct {
+	Path      string
+	Signature string
+	Action    Action
+	// QuarantinedTo is where the file was moved to, if quarantined.
+	QuarantinedTo string
+}
+
+func (e *InfectedError) Error() string {
+	switch e.Action {
+	case Quarantine:
+		return fmt.Sprintf("Infected with %s, quarantined to %s", e.Signature, e.QuarantinedTo)
+	case Delete:
+		return fmt.Sprintf("Infected with %s, deleted", e.Signature)
+	}
+	return fmt.Sprintf("Infected with %s", e.Signature)
+}
+
+func (s *Scanner) Name() string {
+	return "clamd"
+}
+
+func (s *Scanner) FileDownloaded(d gosafely.DownloadedFile) error {
+	fh, err := os.Open(d.Path)
+	if err != nil {
+		return err
+	}
+	signature, err := s.Scan(fh)
+	fh.Close()
+	if err != nil || signature == "" {
+		return err
+	}
+
+	infected := &InfectedError{Path: d.Path, Signature: signature, Action: s.Action}
+	switch s.Action {
+	case Quarantine:
+		infected.QuarantinedTo, err = s.quarantine(d.Path)
+	case Delete:
+		err = os.Remove(d.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("Infected with %s, but could not %s it: %s", signature, s.Action, err)
+	}
+	return infected
+}
+
+// Scan streams r to clamd and returns the name of the signature found, or
+// "" if r is clean.
+func (s *Scanner) Scan(r io.Reader) (string, error) {
+	conn, err := net.DialTimeout(s.Network, s.Address, 30*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	_, err = conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 4+ChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				// clamd closes the connection once the stream is too long,
+				// and explains why in its reply.
+				break
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	conn.Write([]byte{0, 0, 0, 0})
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", err
+	}
+	return parseReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseReply understands clamd's replies to INSTREAM:
+//
+//	stream: OK
+//	stream: Eicar-Test-Signature FOUND
+//	INSTREAM size limit exceeded. ERROR
+func parseReply(reply string) (string, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	}
+	return "", fmt.Errorf("clamd: %s", reply)
+}
+
+func (s *Scanner) quarantine(fp string) (string, error) {
+	if s.QuarantineDir == "" {
+		return "", fmt.Errorf("No quarantine directory set")
+	}
+	err := os.MkdirAll(s.QuarantineDir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(s.QuarantineDir, fmt.Sprintf("%s.%d", filepath.Base(fp), time.Now().UnixNano()))
+	err = os.Rename(fp, dest)
+	if err == nil {
+		return dest, nil
+	}
+
+	// Rename can't move across filesystems, so copy it instead.
+	err = copyFile(fp, dest)
+	if err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+	return dest, os.Remove(fp)
+}
+
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,181 @@
+package clamd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// stubClamd answers INSTREAM like clamd, finding EICAR in any stream that
+// contains it.
+func stubClamd(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+
+				var data bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+						return
+					}
+				}
+
+				if strings.Contains(data.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	defer func(size int) { ChunkSize = size }(ChunkSize)
+	ChunkSize = 16
+
+	s := New(stubClamd(t))
+
+	tables := []struct {
+		data     string
+		expected string
+	}{
+		{"just some logs", ""},
+		{"", ""},
+		{"logs then " + eicar + " then more logs", "Eicar-Test-Signature"},
+	}
+
+	for _, table := range tables {
+		signature, err := s.Scan(strings.NewReader(table.data))
+		if err != nil {
+			t.Fatalf("Scan returned error: %s", err)
+		}
+		if signature != table.expected {
+			t.Errorf("Scan of %q was incorrect, got: %q, want: %q.", table.data, signature, table.expected)
+		}
+	}
+}
+
+func TestFileDownloaded(t *testing.T) {
+	addr := stubClamd(t)
+
+	tables := []struct {
+		data     string
+		action   Action
+		infected bool
+		remains  bool
+	}{
+		{"clean", Flag, false, true},
+		{eicar, Flag, true, true},
+		{eicar, Quarantine, true, false},
+		{eicar, Delete, true, false},
+	}
+
+	for _, table := range tables {
+		dir, err := ioutil.TempDir("", "gosafely")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp := filepath.Join(dir, "logs.tgz")
+		ioutil.WriteFile(fp, []byte(table.data), 0644)
+
+		s := New(addr)
+		s.Action = table.action
+		s.QuarantineDir = filepath.Join(dir, "quarantine")
+
+		err = s.FileDownloaded(gosafely.DownloadedFile{Path: fp})
+
+		var infected *InfectedError
+		if errors.As(err, &infected) != table.infected {
+			t.Errorf("FileDownloaded(%s) was incorrect, got: %v, want infected: %t.", table.action, err, table.infected)
+		}
+		if !table.infected && err != nil {
+			t.Errorf("FileDownloaded(%s) returned error: %s", table.action, err)
+		}
+		if _, err := os.Stat(fp); (err == nil) != table.remains {
+			t.Errorf("FileDownloaded(%s) left the file: %t, want: %t.", table.action, err == nil, table.remains)
+		}
+		if table.action == Quarantine {
+			b, err := ioutil.ReadFile(infected.QuarantinedTo)
+			if err != nil || string(b) != table.data || filepath.Dir(infected.QuarantinedTo) != s.QuarantineDir {
+				t.Errorf("FileDownloaded(%s) quarantined to %s, got: (%q, %v).", table.action, infected.QuarantinedTo, b, err)
+			}
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	tables := []struct {
+		reply     string
+		signature string
+		err       bool
+	}{
+		{"stream: OK", "", false},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND", "Win.Test.EICAR_HDB-1", false},
+		{"INSTREAM size limit exceeded. ERROR", "", true},
+	}
+
+	for _, table := range tables {
+		signature, err := parseReply(table.reply)
+		if signature != table.signature || (err != nil) != table.err {
+			t.Errorf("parseReply(%s) was incorrect, got: (%s, %v), want: (%s, %t).", table.reply, signature, err, table.signature, table.err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tables := []struct {
+		addr    string
+		network string
+		address string
+	}{
+		{"127.0.0.1:3310", "tcp", "127.0.0.1:3310"},
+		{"/var/run/clamav/clamd.ctl", "unix", "/var/run/clamav/clamd.ctl"},
+		{"unix:/tmp/clamd.sock", "unix", "/tmp/clamd.sock"},
+	}
+
+	for _, table := range tables {
+		s := New(table.addr)
+		if s.Network != table.network || s.Address != table.address {
+			t.Errorf("New(%s) was incorrect, got: (%s, %s), want: (%s, %s).", table.addr, s.Network, s.Address, table.network, table.address)
+		}
+	}
+}
@@ -41,7 +41,7 @@ func main() {
 
 		fmt.Printf("%d: %s (%s)\n", i, f.FileName, f.FileSize)
 		fmt.Printf("Downloading file to %s\n", fp)
-		err = api.DownloadFile(pm, p, f, fp)
+		err = api.DownloadFile(pm, p, f, fp, gosafely.BytesProgress(gosafely.ProgressPrintBytes))
 		if err != nil {
 			fmt.Println(err)
 			continue
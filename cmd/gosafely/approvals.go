@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	approvalComment string
+	approvalReason  string
+)
+
+var approvalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "Approve or reject packages held for approval",
+}
+
+var approvalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List packages waiting for your approval",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		packages, err := ssAPI.GetPendingApprovals()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(packages) == 0 {
+			fmt.Println("No packages waiting for approval")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Package ID", "Sent by", "Sent", "Files", "Waiting Recipients"})
+		for _, p := range packages {
+			var emails []string
+			for _, r := range p.PendingRecipients() {
+				emails = append(emails, r.Email)
+			}
+			table.Append([]string{
+				p.PackageID,
+				p.PackageSender,
+				p.PackageTimestamp,
+				fmt.Sprintf("%d", len(p.Files)),
+				strings.Join(emails, ", "),
+			})
+		}
+		table.Render()
+	},
+}
+
+var approvalsApproveCmd = &cobra.Command{
+	Use:   "approve [package-id...]",
+	Short: "Release packages to their recipients",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		for _, packageID := range args {
+			err := ssAPI.ApprovePackage(packageID, approvalComment)
+			if err != nil {
+				fmt.Printf("%s: %s\n", packageID, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Approved %s\n", packageID)
+		}
+	},
+}
+
+var approvalsRejectCmd = &cobra.Command{
+	Use:   "reject [package-id...]",
+	Short: "Stop packages from reaching their recipients",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		confirm(fmt.Sprintf("Reject %d package(s)", len(args)))
+		for _, packageID := range args {
+			err := ssAPI.RejectPackage(packageID, approvalReason)
+			if err != nil {
+				fmt.Printf("%s: %s\n", packageID, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Rejected %s\n", packageID)
+		}
+	},
+}
+
+var approvalsConfirmationsCmd = &cobra.Command{
+	Use:   "confirmations",
+	Short: "Show when each recipient opened a package and downloaded its files",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		p, _, err := getPackage(ssURL)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printConfirmations(p)
+	},
+}
+
+func printConfirmations(p gosafely.Package) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Recipient", "Approval", "File", "IP Address", "Time"})
+	for _, r := range p.Recipients {
+		approval := "Not needed"
+		if r.NeedsApproval {
+			approval = "Waiting"
+		}
+		if len(r.Confirmations) == 0 {
+			table.Append([]string{r.Email, approval, "Not opened", "", ""})
+			continue
+		}
+		for _, c := range r.Confirmations {
+			file := c.FileName
+			if c.FileID == "" {
+				file = "Opened package"
+			}
+			table.Append([]string{r.Email, approval, file, c.IPAddress, c.Timestamp})
+		}
+	}
+	table.Render()
+}
+
+func init() {
+	approvalsApproveCmd.Flags().StringVarP(&approvalComment, "comment", "m", "", "Comment to record with the approval")
+	approvalsRejectCmd.Flags().StringVarP(&approvalReason, "reason", "r", "", "Reason given to the sender")
+	approvalsRejectCmd.MarkFlagRequired("reason")
+	approvalsRejectCmd.Flags().BoolVarP(&packageYes, "yes", "y", false, "Do not ask for confirmation")
+	addPackageFlags(approvalsConfirmationsCmd)
+
+	approvalsCmd.AddCommand(approvalsListCmd, approvalsApproveCmd, approvalsRejectCmd, approvalsConfirmationsCmd)
+	rootCmd.AddCommand(approvalsCmd)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	auditPackageID string
+	auditFrom      string
+	auditTo        string
+	auditFormat    string
+	auditOutput    string
+	auditPageSize  int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Export package activity such as downloads and access attempts",
+	Long: `Export package activity such as downloads and access attempts.
+
+Give --url or --package-id for the activity of a single package. Without
+either the activity of every package in the organization is exported,
+which needs an admin user.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		q := gosafely.ActivityQuery{PackageID: auditPackageID, PageSize: auditPageSize}
+		var err error
+		for _, d := range []struct {
+			flag  string
+			value string
+			dest  *time.Time
+		}{
+			{"--from", auditFrom, &q.From},
+			{"--to", auditTo, &q.To},
+		} {
+			if d.value == "" {
+				continue
+			}
+			*d.dest, err = time.Parse("2006-01-02", d.value)
+			if err != nil {
+				fmt.Printf("%s must be a date like 2018-11-04\n", d.flag)
+				os.Exit(1)
+			}
+		}
+
+		switch {
+		case ssURL != "":
+			p, _, err := getPackage(ssURL)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			q.PackageID = p.PackageID
+		case q.PackageID == "":
+			u, err := ssAPI.UserInformation()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if !u.AdminUser {
+				fmt.Println("Exporting the activity of every package needs an admin user, give --url or --package-id instead")
+				os.Exit(1)
+			}
+		}
+
+		if auditOutput == "" || auditOutput == "-" {
+			_, err = exportActivity(q, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Export into a temporary file that only becomes -o once every
+		// event has been written, so a failed export leaves nothing behind.
+		if _, err := os.Stat(auditOutput); !os.IsNotExist(err) {
+			fmt.Printf("%s: File exists\n", auditOutput)
+			os.Exit(1)
+		}
+		tmp, err := ioutil.TempFile(filepath.Dir(auditOutput), "."+filepath.Base(auditOutput)+".*")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		count, err := exportActivity(q, tmp)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), auditOutput)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d event(s) to %s\n", count, auditOutput)
+	},
+}
+
+// exportActivity writes every event matching q to out in --format and
+// returns how many there were.
+func exportActivity(q gosafely.ActivityQuery, out io.Writer) (int, error) {
+	bw := bufio.NewWriter(out)
+	w, err := gosafely.NewActivityWriter(bw, auditFormat)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = ssAPI.ActivityLog(q, func(e gosafely.ActivityEvent) error {
+		count++
+		return w.Write(e)
+	})
+	// Flush even after an error, so stdout gets what was exported.
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	return count, err
+}
+
+func init() {
+	auditCmd.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL of the package")
+	auditCmd.Flags().StringVarP(&auditPackageID, "package-id", "p", "", "ID of the package")
+	auditCmd.Flags().StringVar(&auditFrom, "from", "", "First day to export, e.g. 2018-11-01")
+	auditCmd.Flags().StringVar(&auditTo, "to", "", "Last day to export, e.g. 2018-11-30")
+	auditCmd.Flags().StringVarP(&auditFormat, "format", "f", gosafely.ExportJSONL, "Export format: jsonl or csv")
+	auditCmd.Flags().StringVarP(&auditOutput, "output", "o", "", "File to export to, defaults to stdout")
+	auditCmd.Flags().IntVar(&auditPageSize, "page-size", gosafely.DefaultActivityPageSize, "Events to request at once")
+	rootCmd.AddCommand(auditCmd)
+}
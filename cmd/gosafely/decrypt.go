@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	decryptServerSecret string
+	decryptKeyCode      string
+	decryptOutput       string
+	decryptArchive      string
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt [parts...]",
+	Short: "Decrypt parts saved with download --encrypted or --archive",
+	Long: `Decrypt parts saved with download --encrypted into the original file.
+
+No network access is needed. Provide either the package link or its
+keyCode. The server secret is read from the server-secret file saved
+alongside the parts, unless --server-secret is given.
+
+With --archive, every file in an archive saved with download --archive is
+decrypted into the --output directory. The server secret is read from the
+archive's manifest, so only the keyCode is needed.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if decryptArchive == "" && len(args) == 0 {
+			return errors.New("requires at least 1 part unless --archive is given")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		keyCode := decryptKeyCode
+		if ssURL != "" {
+			pm, err := ssAPI.GetPackageMetadataFromURL(ssURL)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			keyCode = pm.KeyCode
+		}
+		if decryptArchive != "" {
+			decryptArchiveFiles(keyCode)
+			return
+		}
+		if keyCode == "" {
+			fmt.Println("Either --url or --key-code is required")
+			os.Exit(1)
+		}
+
+		parts := append([]string{}, args...)
+		sort.SliceStable(parts, func(i, j int) bool {
+			return partNumber(parts[i]) < partNumber(parts[j])
+		})
+
+		serverSecret := decryptServerSecret
+		if serverSecret == "" {
+			var err error
+			serverSecret, err = gosafely.ReadServerSecret(filepath.Dir(parts[0]))
+			if err != nil {
+				fmt.Printf("%s, pass --server-secret instead\n", err)
+				os.Exit(1)
+			}
+		}
+
+		err := gosafely.DecryptParts(serverSecret, keyCode, parts, decryptOutput)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Decrypted %d part(s) to %s\n", len(parts), decryptOutput)
+	},
+}
+
+func decryptArchiveFiles(keyCode string) {
+	if keyCode == "" {
+		fmt.Println("Either --url or --key-code is required")
+		os.Exit(1)
+	}
+
+	m, err := gosafely.ReadArchiveManifest(decryptArchive)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = os.MkdirAll(decryptOutput, 0755)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, f := range m.Files {
+		fp := filepath.Join(decryptOutput, filepath.Base(f.FileName))
+		err = gosafely.DecryptParts(m.ServerSecret, keyCode, f.PartPaths(decryptArchive), fp)
+		if err != nil {
+			fmt.Printf("%s: %s\n", f.FileName, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Decrypted %s\n", fp)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// partNumber returns the number of a part file named by
+// EncryptedPartName, so parts sort as 1, 2, ..., 10 rather than 1, 10, 2.
+func partNumber(name string) int {
+	i := strings.LastIndex(name, ".part")
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(name[i+len(".part"):])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func init() {
+	decryptCmd.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely package URL")
+	decryptCmd.Flags().StringVar(&decryptServerSecret, "server-secret", "", "Server secret of the package, if not saved alongside the parts")
+	decryptCmd.Flags().StringVar(&decryptKeyCode, "key-code", "", "keyCode of the package, if --url is not given")
+	decryptCmd.Flags().StringVarP(&decryptOutput, "output", "o", "", "File to write, or directory with --archive")
+	decryptCmd.Flags().StringVar(&decryptArchive, "archive", "", "Archive directory saved with download --archive")
+	decryptCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(decryptCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	dropzoneID      = os.Getenv("SS_DROPZONE_ID")
+	dropzoneName    string
+	dropzoneEmail   string
+	dropzoneMessage string
+)
+
+var dropzoneCmd = &cobra.Command{
+	Use:   "dropzone",
+	Short: "Send files to a SendSafely Dropzone",
+}
+
+var dropzoneSubmitCmd = &cobra.Command{
+	Use:   "submit [files...]",
+	Short: "Upload files and a message to a Dropzone",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if apiURL == "" || dropzoneID == "" {
+			fmt.Println("SS_API_URL environment variable and SS_DROPZONE_ID or --dropzone-id required")
+			os.Exit(1)
+		}
+
+		a := gosafely.NewDropzoneAPI(apiURL, dropzoneID)
+		configureAPI(a)
+
+		fmt.Printf("Uploading %d file(s)\n", len(args))
+		bars := gosafely.NewMultiBar(os.Stdout)
+		p, link, err := a.SubmitDropzone(dropzoneName, dropzoneEmail, dropzoneMessage, args, bars)
+		bars.Flush()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Submitted package %s\n", p.PackageCode)
+		fmt.Println(link)
+	},
+}
+
+func init() {
+	dropzoneSubmitCmd.Flags().StringVarP(&dropzoneID, "dropzone-id", "d", dropzoneID, "Dropzone ID, defaults to SS_DROPZONE_ID")
+	dropzoneSubmitCmd.Flags().StringVarP(&dropzoneName, "name", "n", "", "Your name")
+	dropzoneSubmitCmd.MarkFlagRequired("name")
+	dropzoneSubmitCmd.Flags().StringVarP(&dropzoneEmail, "email", "e", "", "Your email address")
+	dropzoneSubmitCmd.MarkFlagRequired("email")
+	dropzoneSubmitCmd.Flags().StringVarP(&dropzoneMessage, "message", "m", "", "Message to send with the files")
+	dropzoneCmd.AddCommand(dropzoneSubmitCmd)
+
+	rootCmd.AddCommand(dropzoneCmd)
+}
@@ -3,15 +3,21 @@ package main
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/manifoldco/promptui"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
 	gosafely "github.com/stephendotcarter/gosafely/api"
+	"github.com/stephendotcarter/gosafely/clamd"
+	"github.com/stephendotcarter/gosafely/s3sink"
 )
 
 var (
@@ -21,11 +27,44 @@ var (
 	apiKeySecret = os.Getenv("SS_API_KEY_SECRET")
 	ssAPI        *gosafely.API
 	ssURL        string
+	ssPackageID  string
+	ssKeyFile    string
+	downloadAll  bool
+	debug        bool
+	limitRate    string
+	limitFile    string
+	apiRate      float64
+	apiParallel  int
+
+	downloadConcurrency int
+	downloadEncrypted   string
+	downloadArchive     string
+	downloadDest        string
+	s3Endpoint          string
+	s3Region            string
+
+	downloadExtract      bool
+	downloadExtractLimit string
+	downloadKeepArchive  bool
+	downloadMinFree      string
+	downloadMaxFileSize  string
+	downloadClamd        string
+	downloadInfected     string
+	downloadQuarantine   string
+	downloadOnFile       []string
+	downloadOnPackage    []string
 )
 
+// exitInfected is the exit code when a downloaded file was found to be
+// infected, so scripts can tell it apart from a failed download.
+const exitInfected = 3
+
 var rootCmd = &cobra.Command{
 	Use:   "gosafely",
 	Short: "gosafely is a CLI for SendSafely",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		configureAPI(ssAPI)
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -64,25 +103,298 @@ var downloadCmd = &cobra.Command{
 
 		printPackage(p)
 
-		selected, err := getDownloadIndices(len(p.Files))
+		var selected []int64
+		if downloadAll {
+			selected, err = getIndices(allIndices(len(p.Files)), len(p.Files))
+		} else {
+			selected, err = getDownloadIndices(len(p.Files))
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if downloadConcurrency < 1 {
+			downloadConcurrency = 1
+		}
+
+		if downloadEncrypted != "" && downloadArchive != "" {
+			fmt.Println("Only one of --encrypted and --archive can be used")
+			os.Exit(1)
+		}
+
+		sink, err := newSink(downloadDest)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		extractOpts := gosafely.ExtractOptions{Remove: !downloadKeepArchive}
+		if downloadExtract {
+			requireLocalDest(sink, "--extract")
+			limit, err := humanize.ParseBytes(downloadExtractLimit)
+			if err != nil {
+				fmt.Printf("Invalid --extract-limit: %s\n", err)
+				os.Exit(1)
+			}
+			extractOpts.Limit = int64(limit)
+		}
+
+		var kp gosafely.KeyPair
+		if downloadArchive != "" {
+			// Load the signing key before downloading anything so a
+			// missing key doesn't leave an unsigned archive behind.
+			kp, err = gosafely.LoadKeyPair(ssKeyFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		for _, dir := range []string{downloadEncrypted, downloadArchive} {
+			if dir == "" {
+				continue
+			}
+			err = os.MkdirAll(dir, 0700)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		err = preflight(p, selected, sink)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
+		// An address from SS_CLAMD_ADDRESS is a default, so it mustn't stop
+		// downloads that can't be scanned. Only --clamd itself insists.
+		if downloadClamd != "" && !cmd.Flags().Changed("clamd") && !isLocalDest(sink) {
+			fmt.Println("Not scanning with SS_CLAMD_ADDRESS, files are only scanned when downloading to a local directory")
+			downloadClamd = ""
+		}
+		if downloadClamd != "" {
+			requireLocalDest(sink, "--clamd")
+			scanner := clamd.New(downloadClamd)
+			scanner.Action = clamd.Action(downloadInfected)
+			scanner.QuarantineDir = downloadQuarantine
+			switch scanner.Action {
+			case clamd.Flag, clamd.Quarantine, clamd.Delete:
+			default:
+				fmt.Printf("--infected must be %s, %s or %s\n", clamd.Flag, clamd.Quarantine, clamd.Delete)
+				os.Exit(1)
+			}
+			ssAPI.AddFileHook(scanner)
+		}
+
+		// Added last, so only files that passed every other hook are given
+		// to the package hooks.
+		var downloaded []gosafely.DownloadedFile
+		var downloadedMu sync.Mutex
+		if len(downloadOnFile) > 0 || len(downloadOnPackage) > 0 {
+			flag := "--on-file"
+			if len(downloadOnFile) == 0 {
+				flag = "--on-package"
+			}
+			requireLocalDest(sink, flag)
+			for _, command := range downloadOnFile {
+				ssAPI.AddFileHook(gosafely.NewCommandHook(command))
+			}
+			for _, command := range downloadOnPackage {
+				ssAPI.AddPackageHook(gosafely.NewCommandHook(command))
+			}
+			ssAPI.AddFileHook(gosafely.FileHookFunc("collect", func(d gosafely.DownloadedFile) error {
+				downloadedMu.Lock()
+				downloaded = append(downloaded, d)
+				downloadedMu.Unlock()
+				return nil
+			}))
+		}
+
 		fmt.Println("")
-		for _, s := range selected {
-			fp := "./" + p.Files[s].FileName
-			fmt.Printf("Downloading %s\n", p.Files[s].FileName)
-			err = ssAPI.DownloadFile(pm, p, p.Files[s], fp, gosafely.ProgressPrintBytes)
+		bars := gosafely.NewMultiBar(os.Stdout)
+		sem := make(chan struct{}, downloadConcurrency)
+		archived := make([]*gosafely.ArchiveFile, len(selected))
+		extracted := make([]string, len(selected))
+		extractFailed := make([]bool, len(selected))
+		results := make([]error, len(selected))
+		var wg sync.WaitGroup
+		for i, s := range selected {
+			i, f := i, p.Files[s]
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				// Errors are shown against the file's progress bar.
+				switch {
+				case downloadArchive != "":
+					af, err := ssAPI.SaveArchiveFile(pm, p, f, downloadArchive, bars)
+					if err == nil {
+						archived[i] = &af
+					}
+					results[i] = err
+				case downloadEncrypted != "":
+					_, results[i] = ssAPI.SaveEncryptedParts(pm, p, f, downloadEncrypted, bars)
+				default:
+					err := ssAPI.DownloadTo(pm, p, f, sink, f.FileName, bars)
+					results[i] = err
+					// Parts are integrity checked as they are decrypted, so
+					// a successful download can be extracted.
+					if err == nil && downloadExtract && gosafely.IsArchive(f.FileName) {
+						dir, err := gosafely.ExtractArchive(filepath.Join(downloadDest, f.FileName), extractOpts)
+						if err != nil {
+							extracted[i] = fmt.Sprintf("%s: %s", f.FileName, err)
+							extractFailed[i] = true
+						} else {
+							extracted[i] = fmt.Sprintf("Extracted %s to %s", f.FileName, dir)
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		bars.Flush()
+
+		for _, msg := range extracted {
+			if msg != "" {
+				fmt.Println(msg)
+			}
+		}
+
+		code := 0
+		infected := 0
+		for i, err := range results {
+			var ie *clamd.InfectedError
+			var he *gosafely.HookError
+			switch {
+			case errors.As(err, &ie):
+				fmt.Printf("INFECTED %s: %s\n", p.Files[selected[i]].FileName, ie)
+				infected++
+				code = exitInfected
+			case errors.As(err, &he):
+				fmt.Printf("Downloaded %s, but hook %s\n", p.Files[selected[i]].FileName, he)
+				if code == 0 {
+					code = 1
+				}
+			case (err != nil || extractFailed[i]) && code == 0:
+				code = 1
+			}
+		}
+		if downloadClamd != "" {
+			fmt.Printf("Scanned %d file(s), %d infected\n", len(selected), infected)
+		}
+
+		if len(downloadOnPackage) > 0 {
+			for _, err := range ssAPI.RunPackageHooks(p, downloaded) {
+				fmt.Printf("Package hook %s\n", err)
+				if code == 0 {
+					code = 1
+				}
+			}
+		}
+
+		if downloadArchive != "" {
+			m := gosafely.NewArchiveManifest(pm, p)
+			for _, af := range archived {
+				if af != nil {
+					m.Files = append(m.Files, *af)
+				}
+			}
+			err = gosafely.WriteManifest(downloadArchive, m, kp)
 			if err != nil {
 				fmt.Println(err)
+				os.Exit(1)
 			}
-			fmt.Println()
+			fmt.Printf("\nArchived %d of %d file(s) to %s, signed with key %s\n", len(m.Files), len(selected), downloadArchive, kp.Entity.PrimaryKey.KeyIdString())
 		}
+
+		if downloadEncrypted != "" {
+			err = gosafely.WriteServerSecret(downloadEncrypted, p.ServerSecret)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Printf("\nEncrypted parts saved to %s, with the server secret in %s. To decrypt them run:\n", downloadEncrypted, gosafely.ServerSecretFile)
+			fmt.Printf("  gosafely decrypt --url <link> -o <file> <parts...>\n")
+		}
+
+		os.Exit(code)
 	},
 }
 
+// requireLocalDest exits unless files are being decrypted into a local
+// directory, which flag needs to work on them once downloaded.
+func requireLocalDest(sink gosafely.Sink, flag string) {
+	if !isLocalDest(sink) {
+		fmt.Printf("%s only works when downloading to a local directory\n", flag)
+		os.Exit(1)
+	}
+}
+
+func isLocalDest(sink gosafely.Sink) bool {
+	_, ok := sink.(gosafely.DirSink)
+	return ok && downloadEncrypted == "" && downloadArchive == ""
+}
+
+// preflight checks the selected files will fit where they are being
+// downloaded to. Object storage destinations are only checked for size.
+func preflight(p gosafely.Package, selected []int64, sink gosafely.Sink) error {
+	var opts gosafely.PreflightOptions
+	for _, l := range []struct {
+		flag  string
+		value string
+		dest  *int64
+	}{
+		{"--min-free", downloadMinFree, &opts.Margin},
+		{"--max-file-size", downloadMaxFileSize, &opts.MaxFileSize},
+	} {
+		if l.value == "" {
+			continue
+		}
+		n, err := humanize.ParseBytes(l.value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s: %s", l.flag, err)
+		}
+		*l.dest = int64(n)
+	}
+
+	files := make([]gosafely.File, len(selected))
+	for i, s := range selected {
+		files[i] = p.Files[s]
+	}
+
+	dir := downloadDest
+	switch {
+	case downloadArchive != "":
+		dir = downloadArchive
+	case downloadEncrypted != "":
+		dir = downloadEncrypted
+	default:
+		if _, ok := sink.(gosafely.DirSink); !ok {
+			dir = ""
+		}
+	}
+	return gosafely.Preflight(files, dir, opts)
+}
+
+// newSink returns where downloads are written: a local directory, or an
+// object store for s3:// destinations.
+func newSink(dest string) (gosafely.Sink, error) {
+	if strings.HasPrefix(dest, "s3://") {
+		return s3sink.New(dest, s3sink.Options{Endpoint: s3Endpoint, Region: s3Region})
+	}
+	fi, err := os.Stat(dest)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dest)
+	}
+	return gosafely.DirSink(dest), nil
+}
+
 func getDownloadIndices(fc int) ([]int64, error) {
 	validate := func(input string) error {
 		_, err := getIndices(input, fc)
@@ -92,19 +404,14 @@ func getDownloadIndices(fc int) ([]int64, error) {
 		return nil
 	}
 
-	// Default all files selected
-	selectedDefault := []string{}
-	for i := 0; i < fc; i++ {
-		selectedDefault = append(selectedDefault, strconv.Itoa(i))
-	}
-
 	prompt := promptui.Prompt{
 		Label:    "Files",
 		Validate: validate,
 		Templates: &promptui.PromptTemplates{
 			Success: "{{ . | faint }} ",
 		},
-		Default: strings.Join(selectedDefault, ","),
+		// Default all files selected
+		Default: allIndices(fc),
 	}
 	result, err := prompt.Run()
 
@@ -115,6 +422,14 @@ func getDownloadIndices(fc int) ([]int64, error) {
 	return selected, nil
 }
 
+func allIndices(fc int) string {
+	selected := []string{}
+	for i := 0; i < fc; i++ {
+		selected = append(selected, strconv.Itoa(i))
+	}
+	return strings.Join(selected, ",")
+}
+
 func getIndices(input string, fc int) ([]int64, error) {
 	input = strings.Replace(input, " ", "", -1)
 	selected := []int64{}
@@ -135,6 +450,9 @@ func getIndices(input string, fc int) ([]int64, error) {
 func getPackage(packageURL string) (gosafely.Package, gosafely.PackageMetadata, error) {
 	var p gosafely.Package
 	var pm gosafely.PackageMetadata
+	if packageURL == "" {
+		return getPackageWithKeyPair()
+	}
 	pm, err := ssAPI.GetPackageMetadataFromURL(packageURL)
 	if err != nil {
 		return p, pm, err
@@ -146,6 +464,27 @@ func getPackage(packageURL string) (gosafely.Package, gosafely.PackageMetadata,
 	return p, pm, nil
 }
 
+// getPackageWithKeyPair is used instead of a secure link when the package
+// keyCode was encrypted to a registered key pair.
+func getPackageWithKeyPair() (gosafely.Package, gosafely.PackageMetadata, error) {
+	if ssPackageID == "" {
+		return gosafely.Package{}, gosafely.PackageMetadata{}, errors.New("Either --url or --package-id is required")
+	}
+	kp, err := gosafely.LoadKeyPair(ssKeyFile)
+	if err != nil {
+		return gosafely.Package{}, gosafely.PackageMetadata{}, err
+	}
+	return ssAPI.GetPackageWithKeyPair(ssPackageID, kp)
+}
+
+// addPackageFlags lets a command find a package either by secure link or
+// by package ID and a registered key pair.
+func addPackageFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL to query")
+	cmd.Flags().StringVarP(&ssPackageID, "package-id", "p", "", "Package ID, used with --key instead of --url")
+	cmd.Flags().StringVarP(&ssKeyFile, "key", "k", defaultKeyFile(), "Private key registered with SendSafely")
+}
+
 func printPackage(p gosafely.Package) {
 	fmt.Println("")
 	table := tablewriter.NewWriter(os.Stdout)
@@ -173,6 +512,39 @@ func printFiles(files []gosafely.File) {
 	table.Render()
 }
 
+// configureAPI applies the global flags to a.
+func configureAPI(a *gosafely.API) {
+	a.SetLogger(newLogger())
+	a.SetRequestRateLimit(apiRate, 1)
+	a.SetMaxConcurrentRequests(apiParallel)
+
+	for _, l := range []struct {
+		value string
+		set   func(int64)
+	}{
+		{limitRate, a.SetBandwidthLimit},
+		{limitFile, a.SetTransferBandwidthLimit},
+	} {
+		if l.value == "" {
+			continue
+		}
+		r, err := gosafely.ParseByteRate(l.value)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		l.set(r)
+	}
+}
+
+// newLogger returns the logger requests are traced to with --debug, or nil.
+func newLogger() *slog.Logger {
+	if !debug {
+		return nil
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
 func checkEnvVars() {
 	if apiURL == "" || apiKeyID == "" || apiKeySecret == "" {
 		fmt.Println("SS_API_URL, SS_API_KEY_ID and SS_API_KEY_SECRET environment variables required")
@@ -184,14 +556,35 @@ func init() {
 
 	ssAPI = gosafely.NewAPI(apiURL, apiKeyID, apiKeySecret)
 
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log requests to stderr, with secrets redacted")
+	rootCmd.PersistentFlags().StringVar(&limitRate, "limit-rate", "", "Limit total transfer rate, e.g. 5MB/s")
+	rootCmd.PersistentFlags().StringVar(&limitFile, "limit-rate-per-file", "", "Limit the transfer rate of each file, e.g. 1MB/s")
+	rootCmd.PersistentFlags().Float64Var(&apiRate, "api-rate", 0, "Limit API requests per second, 0 for no limit")
+	rootCmd.PersistentFlags().IntVar(&apiParallel, "api-concurrency", 0, "Limit concurrent API requests, 0 for no limit")
+
 	rootCmd.AddCommand(versionCmd)
 
-	listCmd.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL to query")
-	listCmd.MarkFlagRequired("url")
+	addPackageFlags(listCmd)
 	rootCmd.AddCommand(listCmd)
 
-	downloadCmd.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL to query")
-	downloadCmd.MarkFlagRequired("url")
+	addPackageFlags(downloadCmd)
+	downloadCmd.Flags().BoolVarP(&downloadAll, "all", "a", false, "Download all files without prompting")
+	downloadCmd.Flags().IntVarP(&downloadConcurrency, "concurrency", "c", 1, "Number of files to download at once")
+	downloadCmd.Flags().StringVar(&downloadEncrypted, "encrypted", "", "Save the encrypted parts to this directory instead of decrypting them")
+	downloadCmd.Flags().StringVarP(&downloadDest, "dest", "d", ".", "Directory or s3://bucket/prefix to download to")
+	downloadCmd.Flags().StringVar(&s3Endpoint, "s3-endpoint", os.Getenv("SS_S3_ENDPOINT"), "S3 compatible endpoint for s3:// destinations, defaults to SS_S3_ENDPOINT or AWS")
+	downloadCmd.Flags().StringVar(&s3Region, "s3-region", os.Getenv("AWS_REGION"), "Region of the s3:// destination")
+	downloadCmd.Flags().BoolVarP(&downloadExtract, "extract", "x", false, "Extract zip, tar, tar.gz and tar.zst files once downloaded")
+	downloadCmd.Flags().StringVar(&downloadExtractLimit, "extract-limit", "10GiB", "Most data to extract from each archive")
+	downloadCmd.Flags().BoolVar(&downloadKeepArchive, "keep-archive", true, "Keep archives after extracting them")
+	downloadCmd.Flags().StringVar(&downloadMinFree, "min-free", "100MiB", "Space to leave free on the destination after downloading")
+	downloadCmd.Flags().StringVar(&downloadMaxFileSize, "max-file-size", "", "Refuse to download files larger than this, e.g. 20GB")
+	downloadCmd.Flags().StringVar(&downloadClamd, "clamd", os.Getenv("SS_CLAMD_ADDRESS"), "Scan files with clamd at this socket path or host:port, defaults to SS_CLAMD_ADDRESS, which is ignored when not downloading to a local directory")
+	downloadCmd.Flags().StringVar(&downloadInfected, "infected", "flag", "What to do with infected files: flag, quarantine or delete")
+	downloadCmd.Flags().StringVar(&downloadQuarantine, "quarantine-dir", "./quarantine", "Where --infected quarantine moves infected files")
+	downloadCmd.Flags().StringArrayVar(&downloadOnFile, "on-file", nil, "Run this command after each file is downloaded, can be repeated")
+	downloadCmd.Flags().StringArrayVar(&downloadOnPackage, "on-package", nil, "Run this command once the package has been downloaded, can be repeated")
+	downloadCmd.Flags().StringVar(&downloadArchive, "archive", "", "Save the encrypted parts and a manifest signed with --key to this directory")
 	rootCmd.AddCommand(downloadCmd)
 }
 
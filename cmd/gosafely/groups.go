@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	groupName         string
+	groupOrganization bool
+	groupEmail        string
+)
+
+var groupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Manage contact groups",
+}
+
+var groupsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List personal and organization contact groups",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		groups, err := getContactGroups()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printContactGroups(groups)
+	},
+}
+
+var groupsCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a contact group",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		id, err := ssAPI.CreateContactGroup(groupName, groupOrganization)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created contact group %s (%s)\n", groupName, id)
+	},
+}
+
+var groupsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a contact group",
+	Run: func(cmd *cobra.Command, args []string) {
+		g := getContactGroup(groupName)
+		err := ssAPI.DeleteContactGroup(g.ContactGroupID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted contact group %s\n", g.ContactGroupName)
+	},
+}
+
+var groupsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a member to a contact group",
+	Run: func(cmd *cobra.Command, args []string) {
+		g := getContactGroup(groupName)
+		_, err := ssAPI.AddContactGroupMember(g.ContactGroupID, groupEmail)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added %s to %s\n", groupEmail, g.ContactGroupName)
+	},
+}
+
+var groupsRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a member from a contact group",
+	Run: func(cmd *cobra.Command, args []string) {
+		g := getContactGroup(groupName)
+		u, ok := g.FindMember(groupEmail)
+		if !ok {
+			fmt.Printf("%s is not a member of %s\n", groupEmail, g.ContactGroupName)
+			os.Exit(1)
+		}
+		err := ssAPI.RemoveContactGroupMember(g.ContactGroupID, u.UserID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s from %s\n", groupEmail, g.ContactGroupName)
+	},
+}
+
+func getContactGroups() ([]gosafely.ContactGroup, error) {
+	groups, err := ssAPI.GetContactGroups()
+	if err != nil {
+		return nil, err
+	}
+	org, err := ssAPI.GetOrganizationContactGroups()
+	if err != nil {
+		return nil, err
+	}
+	return append(groups, org...), nil
+}
+
+// getContactGroup resolves a contact group from its ID or name.
+func getContactGroup(nameOrID string) gosafely.ContactGroup {
+	checkEnvVars()
+	groups, err := getContactGroups()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	g, ok := gosafely.FindContactGroup(groups, nameOrID)
+	if !ok {
+		fmt.Printf("Could not find contact group %s\n", nameOrID)
+		os.Exit(1)
+	}
+	return g
+}
+
+func printContactGroups(groups []gosafely.ContactGroup) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Group ID", "Contact Group", "Organization", "Members"})
+	table.SetAutoWrapText(false)
+	for _, g := range groups {
+		members := []string{}
+		for _, u := range g.Users {
+			members = append(members, u.UserEmail)
+		}
+		table.Append([]string{
+			g.ContactGroupID,
+			g.ContactGroupName,
+			fmt.Sprintf("%t", g.ContactGroupIsOrganizationGroup),
+			strings.Join(members, "\n"),
+		})
+	}
+	table.Render()
+}
+
+func init() {
+	groupsCmd.AddCommand(groupsListCmd)
+
+	for _, c := range []*cobra.Command{groupsCreateCmd, groupsDeleteCmd, groupsAddCmd, groupsRemoveCmd} {
+		c.Flags().StringVarP(&groupName, "group", "g", "", "Contact group name or ID")
+		c.MarkFlagRequired("group")
+		groupsCmd.AddCommand(c)
+	}
+
+	groupsCreateCmd.Flags().BoolVar(&groupOrganization, "organization", false, "Share the group across the organization")
+
+	for _, c := range []*cobra.Command{groupsAddCmd, groupsRemoveCmd} {
+		c.Flags().StringVarP(&groupEmail, "email", "e", "", "Member email address")
+		c.MarkFlagRequired("email")
+	}
+
+	rootCmd.AddCommand(groupsCmd)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	keyName        string
+	keyEmail       string
+	keyDescription string
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the key pair used to download packages without a link",
+}
+
+var keysGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a key pair and register the public key with SendSafely",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		if _, err := os.Stat(ssKeyFile); !os.IsNotExist(err) {
+			fmt.Printf("%s already exists\n", ssKeyFile)
+			os.Exit(1)
+		}
+
+		if keyEmail == "" {
+			u, err := ssAPI.UserInformation()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			keyEmail = u.Email
+		}
+
+		fmt.Println("Generating key pair")
+		kp, err := gosafely.GenerateKeyPair(keyName, keyEmail)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// Save the private key before registering the public key, so a
+		// key is never registered that nobody can decrypt with.
+		err = os.MkdirAll(filepath.Dir(ssKeyFile), 0700)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = gosafely.SaveKeyPair(kp, ssKeyFile)
+		if err != nil {
+			fmt.Printf("%s: %s\n", ssKeyFile, err)
+			os.Exit(1)
+		}
+
+		err = ssAPI.RegisterPublicKey(&kp, keyDescription)
+		if err != nil {
+			os.Remove(ssKeyFile)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// Save it again to record the public key ID it was registered as.
+		tmp := ssKeyFile + ".tmp"
+		err = gosafely.SaveKeyPair(kp, tmp)
+		if err == nil {
+			err = os.Rename(tmp, ssKeyFile)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			fmt.Printf("Registered public key %s, but could not record its ID in %s: %s\n", kp.PublicKeyID, ssKeyFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Registered public key %s, private key saved to %s\n", kp.PublicKeyID, ssKeyFile)
+	},
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke the public key registered with SendSafely",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		kp, err := gosafely.LoadKeyPair(ssKeyFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = ssAPI.RevokePublicKey(kp.PublicKeyID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked public key %s, %s can be deleted\n", kp.PublicKeyID, ssKeyFile)
+	},
+}
+
+var keysExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print the armored public key, e.g. to verify archives elsewhere",
+	Run: func(cmd *cobra.Command, args []string) {
+		kp, err := gosafely.LoadKeyPair(ssKeyFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		pub, err := kp.ArmoredPublicKey()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Print(pub)
+	},
+}
+
+func defaultKeyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gosafely", "private.key")
+}
+
+func init() {
+	for _, c := range []*cobra.Command{keysGenerateCmd, keysRevokeCmd, keysExportCmd} {
+		c.Flags().StringVarP(&ssKeyFile, "key", "k", defaultKeyFile(), "Private key file")
+		keysCmd.AddCommand(c)
+	}
+
+	keysGenerateCmd.Flags().StringVar(&keyName, "name", "gosafely", "Name on the key")
+	keysGenerateCmd.Flags().StringVar(&keyEmail, "email", "", "Email on the key, defaults to the API user's")
+	keysGenerateCmd.Flags().StringVar(&keyDescription, "description", "gosafely", "Description shown in SendSafely")
+
+	rootCmd.AddCommand(keysCmd)
+}
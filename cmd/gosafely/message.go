@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var messageCmd = &cobra.Command{
+	Use:   "message",
+	Short: "Print the message sent with a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		p, pm, err := getPackage(ssURL)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		m, err := ssAPI.GetPackageMessage(pm, p)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if m == "" {
+			fmt.Println("No message")
+			return
+		}
+		fmt.Println(m)
+	},
+}
+
+func init() {
+	addPackageFlags(messageCmd)
+	rootCmd.AddCommand(messageCmd)
+}
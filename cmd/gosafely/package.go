@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	packageLifeDays int
+	packageLabel    string
+	packageYes      bool
+)
+
+var packageCmd = &cobra.Command{
+	Use:   "package",
+	Short: "Manage the lifecycle of a package",
+}
+
+var packageLifeCmd = &cobra.Command{
+	Use:   "life",
+	Short: "Set the number of days a package is available for",
+	Run: func(cmd *cobra.Command, args []string) {
+		runPackageOp(func(packageID string) error {
+			return ssAPI.UpdatePackageLife(packageID, packageLifeDays)
+		}, fmt.Sprintf("Package life set to %d days", packageLifeDays))
+	},
+}
+
+var packageLabelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Set the label of a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		runPackageOp(func(packageID string) error {
+			return ssAPI.UpdatePackageLabel(packageID, packageLabel)
+		}, "Package label updated")
+	},
+}
+
+var packageExpireCmd = &cobra.Command{
+	Use:   "expire",
+	Short: "Expire a package so recipients can no longer access it",
+	Run: func(cmd *cobra.Command, args []string) {
+		confirm("Expire package")
+		runPackageOp(ssAPI.ExpirePackage, "Package expired")
+	},
+}
+
+var packageDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		confirm("Delete package")
+		runPackageOp(ssAPI.DeletePackage, "Package deleted")
+	},
+}
+
+var packageArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Archive a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		runPackageOp(ssAPI.ArchivePackage, "Package archived")
+	},
+}
+
+var packageUnarchiveCmd = &cobra.Command{
+	Use:   "unarchive",
+	Short: "Unarchive a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		runPackageOp(ssAPI.UnarchivePackage, "Package unarchived")
+	},
+}
+
+func runPackageOp(op func(packageID string) error, done string) {
+	checkEnvVars()
+	p, _, err := getPackage(ssURL)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = op(p.PackageID)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(done)
+}
+
+// confirm asks before doing something that can't be undone, unless --yes
+// was given.
+func confirm(label string) {
+	if packageYes {
+		return
+	}
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	if err != nil {
+		fmt.Println("Aborted")
+		os.Exit(1)
+	}
+}
+
+func init() {
+	for _, c := range []*cobra.Command{packageLifeCmd, packageLabelCmd, packageExpireCmd, packageDeleteCmd, packageArchiveCmd, packageUnarchiveCmd} {
+		c.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL to query")
+		c.MarkFlagRequired("url")
+		packageCmd.AddCommand(c)
+	}
+
+	packageLifeCmd.Flags().IntVarP(&packageLifeDays, "days", "d", 0, "Days the package is available for, 0 for no expiry")
+	packageLifeCmd.MarkFlagRequired("days")
+
+	packageLabelCmd.Flags().StringVarP(&packageLabel, "label", "l", "", "Package label")
+	packageLabelCmd.MarkFlagRequired("label")
+
+	packageExpireCmd.Flags().BoolVarP(&packageYes, "yes", "y", false, "Do not ask for confirmation")
+	packageDeleteCmd.Flags().BoolVarP(&packageYes, "yes", "y", false, "Do not ask for confirmation")
+
+	rootCmd.AddCommand(packageCmd)
+}
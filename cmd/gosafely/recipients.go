@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	recipientEmail       string
+	recipientPhone       string
+	recipientCountryCode string
+	recipientRole        string
+	recipientGroupID     string
+	recipientRemoveGroup bool
+)
+
+var recipientsCmd = &cobra.Command{
+	Use:   "recipients",
+	Short: "Manage the recipients of a package",
+}
+
+var recipientsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the recipients of a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		p, _, err := getPackage(ssURL)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printRecipients(p)
+	},
+}
+
+var recipientsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a recipient to a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		p, _, err := getPackage(ssURL)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		r, err := ssAPI.AddRecipient(p.PackageID, recipientEmail)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added %s\n", r.Email)
+	},
+}
+
+var recipientsRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a recipient from a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		p, r := getRecipient()
+
+		err := ssAPI.RemoveRecipient(p.PackageID, r.RecipientID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s\n", r.Email)
+	},
+}
+
+var recipientsPhoneCmd = &cobra.Command{
+	Use:   "phone",
+	Short: "Set the phone number used to verify a recipient by SMS",
+	Run: func(cmd *cobra.Command, args []string) {
+		p, r := getRecipient()
+
+		err := ssAPI.UpdateRecipientPhone(p.PackageID, r.RecipientID, recipientPhone, recipientCountryCode)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated phone number for %s\n", r.Email)
+	},
+}
+
+var recipientsRoleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Set the role of a recipient",
+	Run: func(cmd *cobra.Command, args []string) {
+		p, r := getRecipient()
+
+		err := ssAPI.UpdateRecipientRole(p.PackageID, r.RecipientID, recipientRole)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated role for %s to %s\n", r.Email, recipientRole)
+	},
+}
+
+var recipientsGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Add a contact group to a package",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+		p, _, err := getPackage(ssURL)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		g := getContactGroup(recipientGroupID)
+		if recipientRemoveGroup {
+			err = ssAPI.RemoveContactGroupFromPackage(p.PackageID, g.ContactGroupID)
+		} else {
+			err = ssAPI.AddContactGroupToPackage(p.PackageID, g.ContactGroupID)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if recipientRemoveGroup {
+			fmt.Printf("Removed contact group %s\n", g.ContactGroupName)
+		} else {
+			fmt.Printf("Added contact group %s\n", g.ContactGroupName)
+		}
+	},
+}
+
+func getRecipient() (gosafely.Package, gosafely.Recipient) {
+	checkEnvVars()
+	p, _, err := getPackage(ssURL)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	r, ok := p.FindRecipient(recipientEmail)
+	if !ok {
+		fmt.Printf("%s is not a recipient of package %s\n", recipientEmail, p.PackageCode)
+		os.Exit(1)
+	}
+	return p, r
+}
+
+func printRecipients(p gosafely.Package) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Email", "Name", "Role", "Needs Approval", "Confirmations"})
+	for _, r := range p.Recipients {
+		table.Append([]string{
+			r.Email,
+			r.FullName,
+			r.RoleName,
+			fmt.Sprintf("%t", r.NeedsApproval),
+			fmt.Sprintf("%d", len(r.Confirmations)),
+		})
+	}
+	table.Render()
+
+	if len(p.ContactGroups) == 0 {
+		return
+	}
+	fmt.Println("")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Group ID", "Contact Group", "Members"})
+	for _, g := range p.ContactGroups {
+		table.Append([]string{
+			g.ContactGroupID,
+			g.ContactGroupName,
+			fmt.Sprintf("%d", len(g.Users)),
+		})
+	}
+	table.Render()
+}
+
+func init() {
+	for _, c := range []*cobra.Command{recipientsListCmd, recipientsAddCmd, recipientsRemoveCmd, recipientsPhoneCmd, recipientsRoleCmd, recipientsGroupCmd} {
+		c.Flags().StringVarP(&ssURL, "url", "u", "", "SendSafely URL to query")
+		c.MarkFlagRequired("url")
+		recipientsCmd.AddCommand(c)
+	}
+
+	for _, c := range []*cobra.Command{recipientsAddCmd, recipientsRemoveCmd, recipientsPhoneCmd, recipientsRoleCmd} {
+		c.Flags().StringVarP(&recipientEmail, "email", "e", "", "Recipient email address")
+		c.MarkFlagRequired("email")
+	}
+
+	recipientsPhoneCmd.Flags().StringVar(&recipientPhone, "phone", "", "Phone number for SMS verification")
+	recipientsPhoneCmd.MarkFlagRequired("phone")
+	recipientsPhoneCmd.Flags().StringVar(&recipientCountryCode, "country-code", "US", "Country code of the phone number")
+
+	recipientsRoleCmd.Flags().StringVar(&recipientRole, "role", "", "Role to give the recipient")
+	recipientsRoleCmd.MarkFlagRequired("role")
+
+	recipientsGroupCmd.Flags().StringVarP(&recipientGroupID, "group", "g", "", "Contact group name or ID")
+	recipientsGroupCmd.MarkFlagRequired("group")
+	recipientsGroupCmd.Flags().BoolVar(&recipientRemoveGroup, "remove", false, "Remove the contact group instead of adding it")
+
+	rootCmd.AddCommand(recipientsCmd)
+}
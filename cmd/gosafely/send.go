@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	sendRecipients []string
+	sendGroups     []string
+	sendMessage    string
+	sendStdinName  string
+	sendPack       gosafely.PackOptions
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send [files...]",
+	Short: "Send files in a new package",
+	Long: `Send files in a new package and print its secure link.
+
+Use - as a file name to send whatever is piped to stdin, e.g.
+
+  kubectl logs my-pod | gosafely send -r customer@test.com --name my-pod.log -
+
+Directories are sent as a single zip or tar.gz archive, made as it is
+uploaded. Use --include and --exclude to choose what goes in it.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		if len(sendRecipients) == 0 && len(sendGroups) == 0 {
+			fmt.Println("At least one --recipient or --group is required")
+			os.Exit(1)
+		}
+
+		groups := []gosafely.ContactGroup{}
+		for _, g := range sendGroups {
+			groups = append(groups, getContactGroup(g))
+		}
+
+		p, pm, err := ssAPI.CreatePackage()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, email := range sendRecipients {
+			_, err = ssAPI.AddRecipient(p.PackageID, email)
+			if err != nil {
+				fmt.Printf("%s: %s\n", email, err)
+				os.Exit(1)
+			}
+		}
+		for _, g := range groups {
+			err = ssAPI.AddContactGroupToPackage(p.PackageID, g.ContactGroupID)
+			if err != nil {
+				fmt.Printf("%s: %s\n", g.ContactGroupName, err)
+				os.Exit(1)
+			}
+		}
+
+		bars := gosafely.NewMultiBar(os.Stdout)
+		for _, fp := range args {
+			switch {
+			case fp == "-":
+				_, err = ssAPI.UploadReader(pm, p, sendStdinName, os.Stdin, -1, bars)
+			case isDir(fp):
+				_, err = ssAPI.UploadDirectory(pm, p, fp, sendPack, bars)
+			default:
+				_, err = ssAPI.UploadFile(pm, p, fp, bars)
+			}
+			if err != nil {
+				// Errors opening fp happen before its progress bar is
+				// drawn, so always print the error.
+				bars.Flush()
+				fmt.Printf("%s: %s\n", fp, err)
+				os.Exit(1)
+			}
+		}
+		bars.Flush()
+
+		if sendMessage != "" {
+			err = ssAPI.SavePackageMessage(pm, p, sendMessage)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		link, err := ssAPI.FinalizePackage(pm, p)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sent package %s\n", p.PackageCode)
+		fmt.Println(link)
+	},
+}
+
+func isDir(fp string) bool {
+	fi, err := os.Stat(fp)
+	return err == nil && fi.IsDir()
+}
+
+func init() {
+	sendCmd.Flags().StringArrayVarP(&sendRecipients, "recipient", "r", nil, "Email address to send to, can be repeated")
+	sendCmd.Flags().StringArrayVarP(&sendGroups, "group", "g", nil, "Contact group name or ID to send to, can be repeated")
+	sendCmd.Flags().StringVarP(&sendMessage, "message", "m", "", "Message to send with the files")
+	sendCmd.Flags().StringVar(&sendStdinName, "name", "stdin", "File name to give the data read from stdin")
+	sendCmd.Flags().StringVar(&sendPack.Format, "dir-format", gosafely.PackZip, "Archive format for directories, zip or tgz")
+	sendCmd.Flags().StringArrayVar(&sendPack.Include, "include", nil, "When sending a directory, only send files whose path or name matches this glob, can be repeated")
+	sendCmd.Flags().StringArrayVar(&sendPack.Exclude, "exclude", nil, "Skip files and directories matching this glob, can be repeated")
+	rootCmd.AddCommand(sendCmd)
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+	"github.com/stephendotcarter/gosafely/metrics"
+)
+
+var (
+	serveListen  string
+	serveToken   string
+	serveMetrics bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve package contents over a local HTTP gateway",
+	Long: `Serve package contents over a local HTTP gateway.
+
+Packages can be addressed directly by secure link:
+
+  GET  /package/?url=<link>                 list the files in the package
+  GET  /package/files/<name>?url=<link>     download a file
+
+or registered once and then addressed by link ID:
+
+  POST /links                              body {"url": "<link>"}, returns the link ID
+  GET  /links/<id>/                        list the files in the package
+  GET  /links/<id>/files/<name>            download a file
+
+Downloads support HTTP Range requests. With --metrics, Prometheus metrics
+are served on /metrics.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkEnvVars()
+
+		g := newGateway(ssAPI, serveToken)
+		if serveMetrics {
+			m := metrics.NewPrometheus()
+			ssAPI.SetInstrumentation(m)
+			g.mux.Handle("/metrics", m.Handler())
+		}
+
+		fmt.Printf("Listening on %s\n", serveListen)
+		srv := &http.Server{
+			Addr:    serveListen,
+			Handler: g,
+			// No write timeout, downloads of large files take as long as
+			// they take.
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+		err := srv.ListenAndServe()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+type gatewayLink struct {
+	id string
+	pm gosafely.PackageMetadata
+	p  gosafely.Package
+	fs *gosafely.PackageFS
+}
+
+type gatewayFile struct {
+	FileID   string    `json:"fileId"`
+	FileName string    `json:"fileName"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"isDir"`
+	Uploaded time.Time `json:"uploaded"`
+	Href     string    `json:"href"`
+}
+
+type gatewayListing struct {
+	LinkID           string        `json:"linkId,omitempty"`
+	PackageCode      string        `json:"packageCode"`
+	PackageSender    string        `json:"packageSender"`
+	PackageTimestamp string        `json:"packageTimestamp"`
+	Path             string        `json:"path"`
+	Files            []gatewayFile `json:"files"`
+}
+
+type gateway struct {
+	api   *gosafely.API
+	token string
+	mux   *http.ServeMux
+
+	mu    sync.Mutex
+	links map[string]*gatewayLink
+	// byURL caches resolved packages, so each Range request doesn't cost
+	// another package lookup.
+	byURL map[string]*gatewayLink
+}
+
+func newGateway(a *gosafely.API, token string) *gateway {
+	g := &gateway{
+		api:   a,
+		token: token,
+		mux:   http.NewServeMux(),
+		links: make(map[string]*gatewayLink),
+		byURL: make(map[string]*gatewayLink),
+	}
+	g.mux.HandleFunc("/links", g.handleRegister)
+	g.mux.HandleFunc("/links/", g.handleLink)
+	g.mux.HandleFunc("/package/", g.handlePackage)
+	return g
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+g.token)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *gateway) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cached, status, err := g.link(body.URL)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// The thread ID is in the package URL, so isn't secret; the link ID
+	// is all that stands between a client and the decrypted files.
+	l := *cached
+	l.id, err = newLinkID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	g.mu.Lock()
+	g.links[l.id] = &l
+	g.mu.Unlock()
+
+	writeJSON(w, map[string]string{
+		"linkId": l.id,
+		"href":   "/links/" + l.id + "/",
+	})
+}
+
+func newLinkID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (g *gateway) handleLink(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/links/")
+	id := rest
+	if i := strings.Index(rest, "/"); i >= 0 {
+		id, rest = rest[:i], rest[i+1:]
+	} else {
+		rest = ""
+	}
+
+	g.mu.Lock()
+	l, ok := g.links[id]
+	g.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	g.serve(w, r, l, "/links/"+id+"/", "", rest)
+}
+
+func (g *gateway) handlePackage(w http.ResponseWriter, r *http.Request) {
+	l, status, err := g.link(r.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	query := "?url=" + url.QueryEscape(r.URL.Query().Get("url"))
+	g.serve(w, r, l, "/package/", query, strings.TrimPrefix(r.URL.Path, "/package/"))
+}
+
+// link resolves a package URL, returning the HTTP status to fail with if
+// it can't be: a malformed URL is the client's fault, anything else is
+// SendSafely's.
+func (g *gateway) link(packageURL string) (*gatewayLink, int, error) {
+	g.mu.Lock()
+	l, ok := g.byURL[packageURL]
+	g.mu.Unlock()
+	if ok {
+		return l, http.StatusOK, nil
+	}
+
+	pm, err := g.api.GetPackageMetadataFromURL(packageURL)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	p, err := g.api.GetPackage(pm.PackageCode)
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+	l = &gatewayLink{pm: pm, p: p, fs: gosafely.NewPackageFS(g.api, pm, p)}
+
+	g.mu.Lock()
+	g.byURL[packageURL] = l
+	g.mu.Unlock()
+	return l, http.StatusOK, nil
+}
+
+// serve handles the part of a request below a package. rest is either
+// empty for the package listing or "files/<name>".
+func (g *gateway) serve(w http.ResponseWriter, r *http.Request, l *gatewayLink, base string, query string, rest string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := "."
+	switch {
+	case rest == "":
+	case rest == "files" || rest == "files/":
+	case strings.HasPrefix(rest, "files/"):
+		name = strings.TrimSuffix(strings.TrimPrefix(rest, "files/"), "/")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	fi, err := fs.Stat(l.fs, name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	if fi.IsDir() {
+		g.serveListing(w, l, base, query, name)
+		return
+	}
+
+	f, err := l.fs.Open(name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	defer f.Close()
+
+	// Setting the content type up front stops ServeContent from reading
+	// the start of the file to sniff it, which would cost a part download.
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", ctype)
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f.(io.ReadSeeker))
+}
+
+func (g *gateway) serveListing(w http.ResponseWriter, l *gatewayLink, base string, query string, name string) {
+	entries, err := fs.ReadDir(l.fs, name)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	listing := gatewayListing{
+		LinkID:           l.id,
+		PackageCode:      l.p.PackageCode,
+		PackageSender:    l.p.PackageSender,
+		PackageTimestamp: l.p.PackageTimestamp,
+		Path:             name,
+		Files:            []gatewayFile{},
+	}
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		gf := gatewayFile{
+			FileName: fi.Name(),
+			Size:     fi.Size(),
+			IsDir:    fi.IsDir(),
+			Uploaded: fi.ModTime(),
+			Href:     base + "files/" + escapePath(path.Join(name, fi.Name())) + query,
+		}
+		if f, ok := fi.Sys().(gosafely.File); ok {
+			gf.FileID = f.FileID
+		}
+		listing.Files = append(listing.Files, gf)
+	}
+
+	writeJSON(w, listing)
+}
+
+// escapePath escapes each element of a slash separated path for use in a
+// URL, so names containing "#", "?" or "%" link to the right file.
+func escapePath(p string) string {
+	elems := strings.Split(p, "/")
+	for i := range elems {
+		elems[i] = url.PathEscape(elems[i])
+	}
+	return strings.Join(elems, "/")
+}
+
+func httpError(w http.ResponseWriter, err error) {
+	switch {
+	case os.IsNotExist(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case os.IsPermission(err):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func init() {
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "l", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", os.Getenv("GOSAFELY_SERVE_TOKEN"), "Bearer token clients must present")
+	serveCmd.Flags().BoolVar(&serveMetrics, "metrics", false, "Serve Prometheus metrics on /metrics")
+	rootCmd.AddCommand(serveCmd)
+}
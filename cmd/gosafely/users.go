@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var (
+	userEmail     string
+	userFirstName string
+	userLastName  string
+	userAdmin     bool
+	userAPIKeyID  string
+)
+
+var usersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Manage the users in your organization, admin users only",
+}
+
+var usersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users in your organization",
+	Run: func(cmd *cobra.Command, args []string) {
+		requireAdmin()
+		users, err := ssAPI.GetOrganizationUsers()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"User ID", "Email", "Name", "Admin", "Enabled", "Last Login"})
+		for _, u := range users {
+			table.Append([]string{
+				u.UserID,
+				u.Email,
+				u.FirstName + " " + u.LastName,
+				fmt.Sprintf("%t", u.AdminUser),
+				fmt.Sprintf("%t", u.Enabled),
+				u.LastLogin,
+			})
+		}
+		table.Render()
+	},
+}
+
+var usersInviteCmd = &cobra.Command{
+	Use:   "invite",
+	Short: "Invite a user to your organization",
+	Run: func(cmd *cobra.Command, args []string) {
+		requireAdmin()
+		_, err := ssAPI.InviteUser(userEmail, userFirstName, userLastName, userAdmin)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Invited %s\n", userEmail)
+	},
+}
+
+var usersDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Stop a user signing in or using their API keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		u := getOrganizationUser(userEmail)
+		confirm("Deactivate " + u.Email)
+		err := ssAPI.DeactivateUser(u.UserID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deactivated %s\n", u.Email)
+	},
+}
+
+var usersKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "List a user's API keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		u := getOrganizationUser(userEmail)
+		keys, err := ssAPI.GetUserAPIKeys(u.UserID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"API Key ID", "Description", "Created", "Last Used"})
+		for _, k := range keys {
+			table.Append([]string{k.APIKeyID, k.Description, k.DateCreated, k.LastUsed})
+		}
+		table.Render()
+	},
+}
+
+var usersRevokeKeyCmd = &cobra.Command{
+	Use:   "revoke-key",
+	Short: "Revoke one of a user's API keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		u := getOrganizationUser(userEmail)
+		confirm(fmt.Sprintf("Revoke API key %s of %s", userAPIKeyID, u.Email))
+		err := ssAPI.RevokeUserAPIKey(u.UserID, userAPIKeyID)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked API key %s of %s\n", userAPIKeyID, u.Email)
+	},
+}
+
+// requireAdmin stops early, with a clearer message than the API gives,
+// if the caller is not an admin user.
+func requireAdmin() {
+	checkEnvVars()
+	u, err := ssAPI.UserInformation()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !u.AdminUser {
+		fmt.Printf("%s is not an admin user\n", u.Email)
+		os.Exit(1)
+	}
+}
+
+// getOrganizationUser resolves a user in the organization from their ID
+// or email address.
+func getOrganizationUser(emailOrID string) gosafely.OrganizationUser {
+	requireAdmin()
+	users, err := ssAPI.GetOrganizationUsers()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	u, ok := gosafely.FindOrganizationUser(users, emailOrID)
+	if !ok {
+		fmt.Printf("Could not find user %s\n", emailOrID)
+		os.Exit(1)
+	}
+	return u
+}
+
+func init() {
+	usersCmd.AddCommand(usersListCmd)
+
+	for _, c := range []*cobra.Command{usersInviteCmd, usersDeactivateCmd, usersKeysCmd, usersRevokeKeyCmd} {
+		c.Flags().StringVarP(&userEmail, "email", "e", "", "User email address, or ID except when inviting")
+		c.MarkFlagRequired("email")
+		usersCmd.AddCommand(c)
+	}
+
+	usersInviteCmd.Flags().StringVar(&userFirstName, "first-name", "", "User's first name")
+	usersInviteCmd.Flags().StringVar(&userLastName, "last-name", "", "User's last name")
+	usersInviteCmd.Flags().BoolVar(&userAdmin, "admin", false, "Make the user an admin user")
+
+	usersRevokeKeyCmd.Flags().StringVar(&userAPIKeyID, "key-id", "", "ID of the API key to revoke")
+	usersRevokeKeyCmd.MarkFlagRequired("key-id")
+
+	usersDeactivateCmd.Flags().BoolVarP(&packageYes, "yes", "y", false, "Do not ask for confirmation")
+	usersRevokeKeyCmd.Flags().BoolVarP(&packageYes, "yes", "y", false, "Do not ask for confirmation")
+
+	rootCmd.AddCommand(usersCmd)
+}
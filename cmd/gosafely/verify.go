@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/openpgp"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+var verifyPublicKey string
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [archive]",
+	Short: "Check an archive saved with download --archive is unaltered",
+	Long: `Check an archive saved with download --archive is unaltered.
+
+The manifest signature is checked against --public-key, or the key pair in
+--key if no public key is given, and every part is checked against the
+hash recorded in the manifest.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyring, err := verifyKeyring()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		m, err := gosafely.VerifyArchive(args[0], keyring)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		parts := 0
+		for _, f := range m.Files {
+			parts += len(f.Parts)
+		}
+		fmt.Printf("Package %s from %s: %d file(s), %d part(s) verified, signed by %s at %s\n",
+			m.PackageID, m.PackageSender, len(m.Files), parts, m.SignerKeyID, m.Created.Format(gosafely.TimestampLayout))
+	},
+}
+
+func verifyKeyring() (openpgp.EntityList, error) {
+	if verifyPublicKey == "" {
+		kp, err := gosafely.LoadKeyPair(ssKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return openpgp.EntityList{kp.Entity}, nil
+	}
+
+	fh, err := os.Open(verifyPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return openpgp.ReadArmoredKeyRing(fh)
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyPublicKey, "public-key", "", "Armored public key the manifest was signed with")
+	verifyCmd.Flags().StringVarP(&ssKeyFile, "key", "k", defaultKeyFile(), "Private key file, used if --public-key is not given")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,136 @@
+// Package metrics exposes the events from an api.API as Prometheus
+// metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+// Prometheus implements api.Instrumentation with Prometheus counters and
+// histograms.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	retries         *prometheus.CounterVec
+	downloadedBytes prometheus.Counter
+	downloadedParts prometheus.Counter
+	decryptDuration prometheus.Histogram
+}
+
+func NewPrometheus() *Prometheus {
+	m := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosafely_requests_total",
+			Help: "SendSafely API requests by endpoint and status code.",
+		}, []string{"method", "endpoint", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gosafely_request_duration_seconds",
+			Help:    "Time until SendSafely API response headers were received.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "gosafely_requests_in_flight",
+			Help: "SendSafely API requests waiting on a response.",
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gosafely_request_retries_total",
+			Help: "SendSafely API requests that were retried.",
+		}, []string{"method", "endpoint"}),
+		downloadedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gosafely_downloaded_bytes_total",
+			Help: "Encrypted bytes downloaded.",
+		}),
+		downloadedParts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gosafely_downloaded_parts_total",
+			Help: "File parts downloaded.",
+		}),
+		decryptDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "gosafely_part_decrypt_duration_seconds",
+			Help:    "Time spent decrypting a file part.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.retries,
+		m.downloadedBytes,
+		m.downloadedParts,
+		m.decryptDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry allows further collectors to be served alongside ours.
+func (m *Prometheus) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Prometheus) RequestStarted(method string, path string) {
+	m.inFlight.Inc()
+}
+
+func (m *Prometheus) RequestFinished(method string, path string, status int, duration time.Duration, err error) {
+	m.inFlight.Dec()
+
+	code := strconv.Itoa(status)
+	if err != nil {
+		code = "error"
+	}
+	endpoint := Endpoint(path)
+	m.requests.WithLabelValues(method, endpoint, code).Inc()
+	m.requestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
+}
+
+func (m *Prometheus) RequestRetried(method string, path string, attempt int, wait time.Duration) {
+	m.retries.WithLabelValues(method, Endpoint(path)).Inc()
+}
+
+func (m *Prometheus) PartDownloaded(f gosafely.File, part int, bytes int64) {
+	m.downloadedParts.Inc()
+	m.downloadedBytes.Add(float64(bytes))
+}
+
+func (m *Prometheus) PartDecrypted(f gosafely.File, part int, duration time.Duration) {
+	m.decryptDuration.Observe(duration.Seconds())
+}
+
+var staticSegments = map[string]bool{
+	"api": true, "v2.0": true, "package": true, "file": true, "download": true,
+	"directory": true, "message": true, "recipient": true, "group": true,
+	"groups": true, "finalize": true, "upload-urls": true, "upload-complete": true,
+	"expire": true, "archive": true, "unarchive": true, "user": true,
+	"enterprise": true, "contactgroup": true, "email": true, "public-key": true,
+	"link": true, "config": true, "verify-credentials": true,
+}
+
+// Endpoint turns a request path into a label with bounded cardinality by
+// replacing IDs with "{id}".
+func Endpoint(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if s != "" && !staticSegments[s] {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,21 @@
+package metrics
+
+import "testing"
+
+func TestEndpoint(t *testing.T) {
+	tables := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/v2.0/package/ABCD-EFGH/file/6e07a288-6382-4ca4-9931-adc972e32797/download/", "/api/v2.0/package/{id}/file/{id}/download/"},
+		{"/api/v2.0/package/ABCD-EFGH/message/[REDACTED]/", "/api/v2.0/package/{id}/message/{id}/"},
+		{"/api/v2.0/user/", "/api/v2.0/user/"},
+	}
+
+	for _, table := range tables {
+		result := Endpoint(table.path)
+		if result != table.expected {
+			t.Errorf("Endpoint of %s was incorrect, got: %s, want: %s.", table.path, result, table.expected)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package s3sink writes downloads to S3 or an S3 compatible object store
+// such as MinIO.
+package s3sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	gosafely "github.com/stephendotcarter/gosafely/api"
+)
+
+// DefaultEndpoint is used when Options doesn't name one.
+var DefaultEndpoint = "https://s3.amazonaws.com"
+
+// PartSize is the size of each part of the multipart uploads. Up to one
+// part per file is buffered in memory.
+var PartSize = uint64(16 * 1024 * 1024)
+
+type Options struct {
+	// Endpoint is the URL of the object store, e.g. http://127.0.0.1:9000.
+	// https is assumed if no scheme is given.
+	Endpoint string
+	Region   string
+	// Creds defaults to the AWS and MinIO environment variables, then the
+	// AWS shared credentials file, then the instance's IAM role.
+	Creds *credentials.Credentials
+}
+
+// Sink implements gosafely.Sink, writing each file to an object under a
+// prefix in a bucket. Package metadata is attached as object tags.
+type Sink struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// New returns a Sink for a destination of the form s3://bucket/prefix.
+func New(dest string, opts Options) (*Sink, error) {
+	bucket, prefix, err := ParseURL(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := opts.Creds
+	if creds == nil {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		})
+	}
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  creds,
+		Secure: u.Scheme == "https",
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sink{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// ParseURL splits s3://bucket/prefix into its bucket and prefix.
+func ParseURL(dest string) (string, string, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("Expected s3://bucket/prefix, got %s", dest)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+func (s *Sink) Create(name string, size int64, tags map[string]string) (gosafely.SinkWriter, error) {
+	if err := gosafely.CheckSinkName(name); err != nil {
+		return nil, err
+	}
+	key := path.Join(s.prefix, name)
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	opts := minio.PutObjectOptions{
+		ContentType: ctype,
+		UserTags:    objectTags(tags),
+		PartSize:    PartSize,
+	}
+
+	pr, pw := io.Pipe()
+	w := &writer{pw: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := s.client.PutObject(context.Background(), s.bucket, key, pr, size, opts)
+		// Unblock any Write still waiting if the upload gave up early.
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// writer feeds a streaming multipart upload. If the upload fails, the
+// multipart upload is aborted so no partial object is left behind.
+type writer struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	return w.pw.Write(b)
+}
+
+func (w *writer) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+func (w *writer) Abort(err error) error {
+	w.pw.CloseWithError(err)
+	<-w.done
+	return nil
+}
+
+// objectTags makes tags safe to use as S3 object tags, which only allow
+// letters, digits, spaces and + - = . _ : / @ and limit their length.
+// Tags that don't fit are silently dropped by the client, so anything else
+// is replaced rather than risk losing the tag.
+func objectTags(tags map[string]string) map[string]string {
+	safe := make(map[string]string, len(tags))
+	for k, v := range tags {
+		safe[truncate(sanitizeTag(k), 128)] = truncate(sanitizeTag(v), 256)
+	}
+	return safe
+}
+
+func sanitizeTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune("+-=._:/@", r) {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package s3sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestParseURL(t *testing.T) {
+	tables := []struct {
+		dest   string
+		bucket string
+		prefix string
+		err    bool
+	}{
+		{"s3://evidence", "evidence", "", false},
+		{"s3://evidence/case-1234/", "evidence", "case-1234", false},
+		{"s3://evidence/cases/1234", "evidence", "cases/1234", false},
+		{"https://evidence/cases", "", "", true},
+		{"s3:///cases", "", "", true},
+	}
+
+	for _, table := range tables {
+		bucket, prefix, err := ParseURL(table.dest)
+		if (err != nil) != table.err || bucket != table.bucket || prefix != table.prefix {
+			t.Errorf("ParseURL(%s) was incorrect, got: (%s, %s, %v), want: (%s, %s, error %t).", table.dest, bucket, prefix, err, table.bucket, table.prefix, table.err)
+		}
+	}
+}
+
+func TestObjectTags(t *testing.T) {
+	tags := objectTags(map[string]string{
+		"sendsafely-file-name":      "logs (1).tgz",
+		"sendsafely-package-sender": "jane@customer.com",
+	})
+
+	expected := map[string]string{
+		"sendsafely-file-name":      "logs _1_.tgz",
+		"sendsafely-package-sender": "jane@customer.com",
+	}
+	for k, v := range expected {
+		if tags[k] != v {
+			t.Errorf("objectTags[%s] was incorrect, got: %q, want: %q.", k, tags[k], v)
+		}
+	}
+}
+
+func TestCreateRefusesPaths(t *testing.T) {
+	// The name is checked before the client is used, so none is needed.
+	s := &Sink{bucket: "evidence", prefix: "case-1234"}
+	for _, name := range []string{"../other-case/logs.tgz", "..", "a/b.txt"} {
+		if _, err := s.Create(name, 5, nil); err == nil {
+			t.Errorf("Create(%s) should have been refused.", name)
+		}
+	}
+}
+
+// TestSinkMinIO runs against a real object store, e.g.
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	GOSAFELY_TEST_S3_ENDPOINT=http://127.0.0.1:9000 MINIO_ACCESS_KEY=minioadmin MINIO_SECRET_KEY=minioadmin go test ./s3sink/
+func TestSinkMinIO(t *testing.T) {
+	endpoint := os.Getenv("GOSAFELY_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("GOSAFELY_TEST_S3_ENDPOINT not set")
+	}
+	ctx := context.Background()
+
+	s, err := New("s3://gosafely-test/case-1234", Options{Endpoint: endpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+	if err != nil {
+		if exists, _ := s.client.BucketExists(ctx, s.bucket); !exists {
+			t.Fatal(err)
+		}
+	}
+
+	data := bytes.Repeat([]byte("gosafely"), 5*1024*1024)
+	w, err := s.Create("logs.tgz", int64(len(data)), map[string]string{"sendsafely-package-id": "ABCD-EFGH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i += 1 << 20 {
+		if _, err := w.Write(data[i : i+1<<20]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("Close returned error: %s", err)
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, "case-1234/logs.tgz", minio.GetObjectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Errorf("Object was incorrect, got %d bytes, want %d.", len(b), len(data))
+	}
+
+	tags, err := s.client.GetObjectTagging(ctx, s.bucket, "case-1234/logs.tgz", minio.GetObjectTaggingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags.ToMap()["sendsafely-package-id"] != "ABCD-EFGH" {
+		t.Errorf("Object tags were incorrect, got: %v", tags.ToMap())
+	}
+
+	w, err = s.Create("aborted.tgz", int64(len(data)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data[:1<<20])
+	w.Abort(errors.New("Download failed"))
+	if _, err := s.client.StatObject(ctx, s.bucket, "case-1234/aborted.tgz", minio.StatObjectOptions{}); err == nil {
+		t.Errorf("Aborted object should not exist.")
+	}
+}